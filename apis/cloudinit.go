@@ -21,6 +21,7 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 
 	"github.com/crossplane-contrib/provider-cloudinit/apis/config/v1alpha1"
+	"github.com/crossplane-contrib/provider-cloudinit/apis/config/v1beta1"
 	cloudinitv1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/v1alpha1"
 )
 
@@ -29,6 +30,7 @@ func init() {
 	AddToSchemes = append(AddToSchemes,
 		cloudinitv1alpha1.SchemeBuilder.AddToScheme,
 		v1alpha1.SchemeBuilder.AddToScheme,
+		v1beta1.SchemeBuilder.AddToScheme,
 	)
 }
 
@@ -0,0 +1,119 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+)
+
+// TypePolicy indicates whether the rendered document of a Config satisfies
+// the policy rules of its ProviderConfig.
+const TypePolicy xpv1.ConditionType = "PolicyCompliant"
+
+// TypeNamespaces indicates whether a Config reads and writes only in the
+// namespaces its ProviderConfig allows.
+const TypeNamespaces xpv1.ConditionType = "NamespacesAllowed"
+
+// TypeProfile indicates whether the output of a Config can be read by the
+// consumer of its output profile.
+const TypeProfile xpv1.ConditionType = "ProfileCompatible"
+
+// Reasons a Config does or does not satisfy its policy.
+const (
+	ReasonPolicySatisfied xpv1.ConditionReason = "RulesSatisfied"
+	ReasonPolicyViolated  xpv1.ConditionReason = "RuleViolated"
+
+	ReasonNamespacesAllowed xpv1.ConditionReason = "NamespacesAllowed"
+	ReasonNamespaceDenied   xpv1.ConditionReason = "NamespaceDenied"
+
+	ReasonProfileCompatible   xpv1.ConditionReason = "ProfileCompatible"
+	ReasonProfileIncompatible xpv1.ConditionReason = "ProfileIncompatible"
+)
+
+// PolicySatisfied returns a condition that indicates the rendered document
+// satisfies all policy rules.
+func PolicySatisfied() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypePolicy,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonPolicySatisfied,
+	}
+}
+
+// PolicyViolated returns a condition that indicates the named rule is not
+// satisfied by the rendered document.
+func PolicyViolated(rule, message string) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypePolicy,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonPolicyViolated,
+		Message:            fmt.Sprintf("rule %q: %s", rule, message),
+	}
+}
+
+// NamespacesAllowed returns a condition that indicates all sources and
+// outputs are in allowed namespaces.
+func NamespacesAllowed() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeNamespaces,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonNamespacesAllowed,
+	}
+}
+
+// NamespaceDenied returns a condition that indicates a source or output is
+// not allowed by the ProviderConfig.
+func NamespaceDenied(message string) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeNamespaces,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonNamespaceDenied,
+		Message:            message,
+	}
+}
+
+// ProfileCompatible returns a condition that indicates the output can be read
+// by the consumer of its output profile.
+func ProfileCompatible() xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeProfile,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonProfileCompatible,
+	}
+}
+
+// ProfileIncompatible returns a condition that indicates the settings of a
+// Config produce output the consumer of its output profile cannot read.
+func ProfileIncompatible(message string) xpv1.Condition {
+	return xpv1.Condition{
+		Type:               TypeProfile,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonProfileIncompatible,
+		Message:            message,
+	}
+}
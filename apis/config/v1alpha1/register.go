@@ -45,6 +45,15 @@ var (
 	ConfigGroupVersionKind = SchemeGroupVersion.WithKind(ConfigKind)
 )
 
+// NamespacedConfig type metadata.
+var (
+	NamespacedConfigKind             = reflect.TypeOf(NamespacedConfig{}).Name()
+	NamespacedConfigGroupKind        = schema.GroupKind{Group: Group, Kind: NamespacedConfigKind}.String()
+	NamespacedConfigKindAPIVersion   = NamespacedConfigKind + "." + SchemeGroupVersion.String()
+	NamespacedConfigGroupVersionKind = SchemeGroupVersion.WithKind(NamespacedConfigKind)
+)
+
 func init() {
 	SchemeBuilder.Register(&Config{}, &ConfigList{})
+	SchemeBuilder.Register(&NamespacedConfig{}, &NamespacedConfigList{})
 }
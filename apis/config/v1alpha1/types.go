@@ -33,7 +33,10 @@ type PartSpec struct {
 
 // NamespacedName represents a namespaced object name
 type NamespacedName struct {
-	Namespace string `json:"namespace"`
+	// Namespace of the object. It is required by Config, and defaults to
+	// (and must equal) the namespace of a NamespacedConfig.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
 	Name      string `json:"name"`
 }
 
@@ -44,33 +47,522 @@ type DataKeySelector struct {
 	Optional       bool   `json:"optional,omitempty"`
 }
 
+// OCIArtifactSelector defines required spec to access a file of an OCI
+// registry artifact
+type OCIArtifactSelector struct {
+	// Image is the artifact reference, e.g. registry.example.com/bootstrap:v1
+	Image string `json:"image"`
+
+	// Digest pins the artifact manifest, e.g. sha256:3b4f... The content is
+	// only used when the fetched manifest matches this digest.
+	Digest string `json:"digest,omitempty"`
+
+	// MediaType selects the first layer with this media type
+	MediaType string `json:"mediaType,omitempty"`
+
+	// Path selects the layer titled with this path (as pushed by oras), or
+	// the file at this path within a tar layer
+	Path string `json:"path,omitempty"`
+
+	// PullSecretRef references a kubernetes.io/dockerconfigjson Secret
+	// holding the registry credentials
+	PullSecretRef *NamespacedName `json:"pullSecretRef,omitempty"`
+
+	// Insecure uses plain HTTP to reach the registry
+	Insecure bool `json:"insecure,omitempty"`
+
+	// Optional skips the part when the registry does not know the artifact.
+	// Artifacts pinned by digest are never skipped, nor are artifacts that
+	// cannot be pulled for other reasons, such as failed authentication.
+	Optional bool `json:"optional,omitempty"`
+}
+
 // ContentFromSource represents source of a value
 type ContentFromSource struct {
-	ConfigMapKeyRef *DataKeySelector `json:"configMapKeyRef,omitempty"`
-	SecretKeyRef    *DataKeySelector `json:"secretKeyRef,omitempty"`
+	ConfigMapKeyRef *DataKeySelector     `json:"configMapKeyRef,omitempty"`
+	SecretKeyRef    *DataKeySelector     `json:"secretKeyRef,omitempty"`
+	OCIArtifactRef  *OCIArtifactSelector `json:"ociArtifactRef,omitempty"`
+}
+
+// OutputKind is the kind of object rendered cloud-init data is written to
+type OutputKind string
+
+// Supported output kinds.
+const (
+	OutputKindConfigMap OutputKind = "ConfigMap"
+	OutputKindSecret    OutputKind = "Secret"
+)
+
+// Labels set by the provider.
+const (
+	// LabelKeyOwnerUID is set on output objects to the UID of the Config or
+	// NamespacedConfig writing them. Configs only update and delete outputs
+	// labelled with their UID.
+	LabelKeyOwnerUID = "cloudinit.crossplane.io/owner-uid"
+
+	// LabelKeyResourceNamespace is set on the ProviderConfigUsages of
+	// NamespacedConfigs to their namespace
+	LabelKeyResourceNamespace = "cloudinit.crossplane.io/resource-namespace"
+)
+
+// OutputFormat is the format rendered cloud-init data is written in
+type OutputFormat string
+
+// Supported output formats.
+const (
+	// OutputFormatKeys writes the user-data, and any NoCloud seed, as keys
+	// of the output object
+	OutputFormatKeys OutputFormat = "Keys"
+
+	// OutputFormatISO9660 writes a seed as an ISO9660 image, labelled
+	// cidata for NoCloud and config-2 for config drives
+	OutputFormatISO9660 OutputFormat = "ISO9660"
+
+	// OutputFormatTar writes a seed as a tar archive
+	OutputFormatTar OutputFormat = "Tar"
+
+	// OutputFormatIgnition writes the Ignition config cloud-config parts
+	// translate to, for Flatcar Container Linux and Fedora CoreOS
+	OutputFormatIgnition OutputFormat = "Ignition"
+)
+
+// OutputProfile writes the output in the layout a consumer of cloud-init data
+// expects
+type OutputProfile string
+
+// Output profiles.
+const (
+	// OutputProfileKubeVirt writes a Secret for the secretRef of a KubeVirt
+	// cloudInitNoCloud or cloudInitConfigDrive volume
+	OutputProfileKubeVirt OutputProfile = "KubeVirt"
+
+	// OutputProfileClusterAPI writes a Cluster API bootstrap data Secret
+	OutputProfileClusterAPI OutputProfile = "ClusterAPI"
+
+	// OutputProfileVMwareGuestInfo writes the guestinfo values of a vSphere
+	// virtual machine's extraConfig
+	OutputProfileVMwareGuestInfo OutputProfile = "VMwareGuestInfo"
+
+	// OutputProfileLXD writes the cloud-init config keys of an LXD or Incus
+	// instance or profile
+	OutputProfileLXD OutputProfile = "LXD"
+)
+
+// A ClusterAPIOutput configures the ClusterAPI output profile
+type ClusterAPIOutput struct {
+	// ClusterName is the name of the Cluster the bootstrap data is for. The
+	// Secret is labelled with it.
+	ClusterName string `json:"clusterName"`
+}
+
+// A KubeVirtOutput configures the KubeVirt output profile
+type KubeVirtOutput struct {
+	// VirtualMachineName names a VirtualMachine in the namespace of the
+	// output whose cloud-init volume is patched to reference the Secret
+	// +optional
+	VirtualMachineName string `json:"virtualMachineName,omitempty"`
+
+	// VolumeName is the name of the cloud-init volume of the VirtualMachine.
+	// It defaults to cloudinitdisk.
+	// +optional
+	VolumeName string `json:"volumeName,omitempty"`
+}
+
+// OutputSelector defines the object and key rendered cloud-init data is
+// written to
+type OutputSelector struct {
+	DataKeySelector `json:",inline"`
+
+	// Kind is the kind of object written. It defaults to the ProviderConfig
+	// default output kind, or ConfigMap.
+	// +kubebuilder:validation:Enum=ConfigMap;Secret
+	// +optional
+	Kind OutputKind `json:"kind,omitempty"`
+
+	// Labels are set on the written object, in addition to the
+	// ProviderConfig default labels.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Format is the format the output is written in. Keys writes the
+	// user-data, and the files of a NoCloud seed or config drive, as keys of
+	// the object. ISO9660 and Tar write the seed, which is a NoCloud seed
+	// unless configDrive is set, as an image or archive to the key. The key
+	// defaults to cidata.iso, cidata.tar, config-2.iso or config-2.tar.
+	// Ignition writes an Ignition v3 config translated from the cloud-config
+	// parts to the key, which defaults to config.ign. Only users, groups,
+	// ssh_authorized_keys, write_files, hostname and fqdn are translated.
+	// +kubebuilder:validation:Enum=Keys;ISO9660;Tar;Ignition
+	// +optional
+	Format OutputFormat `json:"format,omitempty"`
+
+	// Profile writes the output in the layout a consumer expects, in place
+	// of the key and format. KubeVirt writes a Secret holding the
+	// plain user-data at the userdata key and any network configuration at
+	// the networkdata key. ClusterAPI writes a cluster.x-k8s.io/secret
+	// Secret holding the plain user-data at the value key, which Machines
+	// can reference as their bootstrap data. VMwareGuestInfo writes the
+	// guestinfo.userdata and guestinfo.metadata keys, and their encodings,
+	// which are gzip+base64 unless gzip or base64Encode are disabled. LXD
+	// writes the plain cloud-init.user-data, cloud-init.vendor-data and
+	// cloud-init.network-config keys.
+	// +kubebuilder:validation:Enum=KubeVirt;ClusterAPI;VMwareGuestInfo;LXD
+	// +optional
+	Profile OutputProfile `json:"profile,omitempty"`
+
+	// KubeVirt configures the KubeVirt profile
+	// +optional
+	KubeVirt *KubeVirtOutput `json:"kubeVirt,omitempty"`
+
+	// ClusterAPI configures the ClusterAPI profile, and is required by it
+	// +optional
+	ClusterAPI *ClusterAPIOutput `json:"clusterAPI,omitempty"`
 }
 
+// Target is the agent that reads the rendered document on the instance
+type Target string
+
+// Targets.
+const (
+	// TargetCloudInit renders for cloud-init
+	TargetCloudInit Target = "CloudInit"
+
+	// TargetCloudbaseInit renders for cloudbase-init on Windows
+	TargetCloudbaseInit Target = "CloudbaseInit"
+)
+
 // ConfigParameters are the configurable fields of a Config.
 type ConfigParameters struct {
-	Gzip         bool `json:"gzip,omitempty"`
-	Base64Encode bool `json:"base64Encode,omitempty"`
+	// Gzip compresses the rendered document. It defaults to the
+	// ProviderConfig default, or false.
+	// +optional
+	Gzip *bool `json:"gzip,omitempty"`
+
+	// Base64Encode encodes the rendered document. It defaults to the
+	// ProviderConfig default, or false.
+	// +optional
+	Base64Encode *bool `json:"base64Encode,omitempty"`
 
 	// Boundary is the optional mime-boundary. It defaults to a random UUIDv4
 	Boundary string `json:"boundary,omitempty"`
 
 	Parts []PartSpec `json:"parts,omitempty"`
+
+	// Target is the agent the document is rendered for, CloudInit by
+	// default. CloudbaseInit detects the content type of PowerShell, cmd
+	// and EC2 style scripts, and rejects content types and cloud-config keys
+	// cloudbase-init does not handle.
+	// +kubebuilder:validation:Enum=CloudInit;CloudbaseInit
+	// +optional
+	Target Target `json:"target,omitempty"`
+
+	// ServiceAccountRef names a ServiceAccount the provider impersonates to
+	// read the sources of parts and write the output, so that its RBAC
+	// decides what may be read and written. It defaults to the
+	// ServiceAccount of the ProviderConfig, or the provider's own identity.
+	// +optional
+	ServiceAccountRef *NamespacedName `json:"serviceAccountRef,omitempty"`
+
+	// NoCloud writes a NoCloud seed to the output: the rendered document at
+	// the user-data key, alongside meta-data, network-config and vendor-data
+	// keys.
+	// +optional
+	NoCloud *NoCloudSeed `json:"noCloud,omitempty"`
+
+	// ConfigDrive writes an OpenStack config drive to the output: the
+	// rendered document as openstack/latest/user_data, alongside
+	// meta_data.json and network_data.json. It may not be set with noCloud.
+	// +optional
+	ConfigDrive *ConfigDrive `json:"configDrive,omitempty"`
+
+	// NetworkConfig is written to the network-config of the NoCloud seed, in
+	// place of noCloud.networkConfig. It requires a NoCloud seed or the
+	// KubeVirt, VMwareGuestInfo or LXD output profile.
+	// +optional
+	NetworkConfig *NetworkConfig `json:"networkConfig,omitempty"`
+}
+
+// A ConfigDrive defines the files of an OpenStack config drive besides
+// user_data
+type ConfigDrive struct {
+	// +optional
+	MetaData ConfigDriveMetaData `json:"metaData,omitempty"`
+
+	// NetworkData is written to network_data.json when set. It is an
+	// OpenStack network data document, as JSON.
+	// +optional
+	NetworkData string `json:"networkData,omitempty"`
+}
+
+// ConfigDriveMetaData is written to the meta_data.json of a config drive
+type ConfigDriveMetaData struct {
+	// UUID identifies the instance to cloud-init, which runs per-instance
+	// modules again when it changes. It defaults to a UUID derived from a
+	// hash of the config drive, so that changes to it are applied.
+	// +optional
+	UUID string `json:"uuid,omitempty"`
+
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// +optional
+	Hostname string `json:"hostname,omitempty"`
+
+	// +optional
+	AvailabilityZone string `json:"availabilityZone,omitempty"`
+
+	// +optional
+	ProjectID string `json:"projectID,omitempty"`
+
+	// PublicKeys are SSH public keys, by name
+	// +optional
+	PublicKeys map[string]string `json:"publicKeys,omitempty"`
+
+	// Meta are arbitrary instance metadata
+	// +optional
+	Meta map[string]string `json:"meta,omitempty"`
+}
+
+// A NoCloudSeed defines the keys of a NoCloud seed besides user-data
+type NoCloudSeed struct {
+	// +optional
+	MetaData NoCloudMetaData `json:"metaData,omitempty"`
+
+	// NetworkConfig is written to the network-config key when set. It is a
+	// cloud-init network configuration, as YAML. Prefer the typed
+	// forProvider.networkConfig, which is validated.
+	// +optional
+	NetworkConfig string `json:"networkConfig,omitempty"`
+
+	// VendorData is written to the vendor-data key when set
+	// +optional
+	VendorData string `json:"vendorData,omitempty"`
+}
+
+// NoCloudMetaData is written to the meta-data key of a NoCloud seed
+type NoCloudMetaData struct {
+	// InstanceID identifies the instance to cloud-init, which runs
+	// per-instance modules again when it changes. It defaults to a hash of
+	// the seed, so that changes to the seed are applied.
+	// +optional
+	InstanceID string `json:"instanceID,omitempty"`
+
+	// +optional
+	LocalHostname string `json:"localHostname,omitempty"`
+}
+
+// A NetworkConfig is a typed cloud-init network configuration. It is written
+// to the network-config of NoCloud seeds in the netplan style version 2
+// format, or translated to version 1 for images whose cloud-init predates
+// version 2.
+type NetworkConfig struct {
+	// Version of the network-config written, 2 by default
+	// +kubebuilder:validation:Enum=1;2
+	// +optional
+	Version int `json:"version,omitempty"`
+
+	// Ethernets are physical interfaces, by ID
+	// +optional
+	Ethernets map[string]Ethernet `json:"ethernets,omitempty"`
+
+	// Bonds are bonded interfaces, by name
+	// +optional
+	Bonds map[string]Bond `json:"bonds,omitempty"`
+
+	// VLANs are VLAN interfaces, by name
+	// +optional
+	VLANs map[string]VLAN `json:"vlans,omitempty"`
+
+	// Bridges are bridge interfaces, by name
+	// +optional
+	Bridges map[string]Bridge `json:"bridges,omitempty"`
+}
+
+// InterfaceSettings are the addressing settings common to all interfaces
+type InterfaceSettings struct {
+	// +optional
+	DHCP4 *bool `json:"dhcp4,omitempty"`
+
+	// +optional
+	DHCP6 *bool `json:"dhcp6,omitempty"`
+
+	// Addresses are static addresses in CIDR notation, e.g. 10.0.0.2/24
+	// +optional
+	Addresses []string `json:"addresses,omitempty"`
+
+	// +optional
+	Gateway4 string `json:"gateway4,omitempty"`
+
+	// +optional
+	Gateway6 string `json:"gateway6,omitempty"`
+
+	// +optional
+	MTU *int `json:"mtu,omitempty"`
+
+	// +optional
+	Nameservers *Nameservers `json:"nameservers,omitempty"`
+
+	// +optional
+	Routes []Route `json:"routes,omitempty"`
+}
+
+// Nameservers configure DNS resolution
+type Nameservers struct {
+	// +optional
+	Addresses []string `json:"addresses,omitempty"`
+
+	// Search domains
+	// +optional
+	Search []string `json:"search,omitempty"`
+}
+
+// A Route is a static route
+type Route struct {
+	// To is the destination in CIDR notation, or default
+	To string `json:"to"`
+
+	// Via is the gateway address
+	Via string `json:"via"`
+
+	// +optional
+	Metric *int `json:"metric,omitempty"`
+}
+
+// An Ethernet is a physical interface
+type Ethernet struct {
+	InterfaceSettings `json:",inline"`
+
+	// Match selects the interface by its properties rather than its ID
+	// +optional
+	Match *Match `json:"match,omitempty"`
+
+	// SetName renames the matched interface
+	// +optional
+	SetName string `json:"setName,omitempty"`
+}
+
+// A Match selects physical interfaces
+type Match struct {
+	// Name of the interface, which may be a glob
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// +optional
+	MACAddress string `json:"macAddress,omitempty"`
+
+	// Driver of the interface, which may be a glob
+	// +optional
+	Driver string `json:"driver,omitempty"`
+}
+
+// A Bond aggregates interfaces
+type Bond struct {
+	InterfaceSettings `json:",inline"`
+
+	// Interfaces are the IDs of the bonded interfaces
+	Interfaces []string `json:"interfaces"`
+
+	// +optional
+	Parameters *BondParameters `json:"parameters,omitempty"`
+}
+
+// BondParameters configure a bond
+type BondParameters struct {
+	// Mode of the bond, e.g. active-backup or 802.3ad
+	// +optional
+	Mode string `json:"mode,omitempty"`
+
+	// Primary is the ID of the primary interface of active-backup bonds
+	// +optional
+	Primary string `json:"primary,omitempty"`
+
+	// MIIMonitorInterval is the link monitoring interval in milliseconds
+	// +optional
+	MIIMonitorInterval *int `json:"miiMonitorInterval,omitempty"`
+
+	// +optional
+	LACPRate string `json:"lacpRate,omitempty"`
+
+	// +optional
+	TransmitHashPolicy string `json:"transmitHashPolicy,omitempty"`
+}
+
+// A VLAN is a tagged interface on a link
+type VLAN struct {
+	InterfaceSettings `json:",inline"`
+
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=4094
+	ID int `json:"id"`
+
+	// Link is the ID of the underlying interface
+	Link string `json:"link"`
+}
+
+// A Bridge connects interfaces
+type Bridge struct {
+	InterfaceSettings `json:",inline"`
+
+	// Interfaces are the IDs of the bridged interfaces
+	// +optional
+	Interfaces []string `json:"interfaces,omitempty"`
+
+	// +optional
+	Parameters *BridgeParameters `json:"parameters,omitempty"`
+}
+
+// BridgeParameters configure a bridge
+type BridgeParameters struct {
+	// +optional
+	STP *bool `json:"stp,omitempty"`
+
+	// ForwardDelay is the forwarding delay in seconds
+	// +optional
+	ForwardDelay *int `json:"forwardDelay,omitempty"`
+}
+
+// PartPosition is the position of an injected part relative to the parts of
+// a Config
+type PartPosition string
+
+// Injected part positions.
+const (
+	PartPositionPrepend PartPosition = "Prepend"
+	PartPositionAppend  PartPosition = "Append"
+)
+
+// InjectedPart describes a baseline part that was rendered into a Config by
+// its ProviderConfig
+type InjectedPart struct {
+	// ProviderConfig is the name of the ProviderConfig that injected the part
+	ProviderConfig string `json:"providerConfig"`
+
+	Position PartPosition `json:"position"`
+
+	// Index is the index of the part in the rendered document
+	Index int `json:"index"`
+
+	Filename    string `json:"filename,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+
+	// SHA256 is the hex encoded sha256 digest of the part content
+	SHA256 string `json:"sha256"`
 }
 
 // ConfigObservation are the observable fields of a Config.
 type ConfigObservation struct {
 	State string `json:"state,omitempty"`
+
+	// InjectedParts are the baseline parts rendered into this Config by its
+	// ProviderConfig
+	InjectedParts []InjectedPart `json:"injectedParts,omitempty"`
 }
 
 // A ConfigSpec defines the desired state of a Config.
 type ConfigSpec struct {
 	xpv1.ResourceSpec   `json:",inline"`
 	ForProvider         ConfigParameters `json:"forProvider"`
-	WriteCloudInitToRef *DataKeySelector `json:"writeCloudInitToRef,omitempty"`
+	WriteCloudInitToRef *OutputSelector  `json:"writeCloudInitToRef,omitempty"`
 }
 
 // A ConfigStatus represents the observed state of a Config.
@@ -87,6 +579,7 @@ type ConfigStatus struct {
 // A Config is an example API type
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="CONFIGMAP",type="string",JSONPath=".spec.writeCloudInitToRef.name"
+// +kubebuilder:printcolumn:name="PROVIDER-CONFIG",type="string",JSONPath=".spec.providerConfigRef.name",priority=1
 // +kubebuilder:printcolumn:name="SYNCED",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status"
 // +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
 // +kubebuilder:printcolumn:name="STATE",type="string",JSONPath=".status.atProvider.state"
@@ -108,3 +601,32 @@ type ConfigList struct {
 	metav1.ListMeta `json:"metadata,omitempty"`
 	Items           []Config `json:"items"`
 }
+
+// +kubebuilder:object:root=true
+// +kubebuilder:storageversion
+
+// A NamespacedConfig renders cloud-init data like a Config. It may only read
+// sources from, and write its output to, its own namespace.
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="OUTPUT",type="string",JSONPath=".spec.writeCloudInitToRef.name"
+// +kubebuilder:printcolumn:name="PROVIDER-CONFIG",type="string",JSONPath=".spec.providerConfigRef.name",priority=1
+// +kubebuilder:printcolumn:name="SYNCED",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status"
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:resource:scope=Namespaced,categories={crossplane,provider,cloudinit}
+type NamespacedConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ConfigSpec   `json:"spec"`
+	Status ConfigStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// NamespacedConfigList contains a list of NamespacedConfig
+type NamespacedConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NamespacedConfig `json:"items"`
+}
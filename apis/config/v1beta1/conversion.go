@@ -0,0 +1,148 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/crossplane-contrib/provider-cloudinit/apis/config/v1alpha1"
+)
+
+const (
+	errHubType    = "cannot convert to or from hub of an unexpected type"
+	errConvertFmt = "cannot convert %s"
+)
+
+// ConvertTo converts this Config to the v1alpha1 hub version.
+func (c *Config) ConvertTo(hub conversion.Hub) error {
+	dst, ok := hub.(*v1alpha1.Config)
+	if !ok {
+		return errors.New(errHubType)
+	}
+	dst.ObjectMeta = c.ObjectMeta
+	return errors.Wrapf(convertTo(&c.Spec, &c.Status, &dst.Spec, &dst.Status), errConvertFmt, c.GetName())
+}
+
+// ConvertFrom converts the v1alpha1 hub version to this Config.
+func (c *Config) ConvertFrom(hub conversion.Hub) error {
+	src, ok := hub.(*v1alpha1.Config)
+	if !ok {
+		return errors.New(errHubType)
+	}
+	c.ObjectMeta = src.ObjectMeta
+	return errors.Wrapf(convertFrom(&src.Spec, &src.Status, &c.Spec, &c.Status), errConvertFmt, c.GetName())
+}
+
+// ConvertTo converts this NamespacedConfig to the v1alpha1 hub version.
+func (c *NamespacedConfig) ConvertTo(hub conversion.Hub) error {
+	dst, ok := hub.(*v1alpha1.NamespacedConfig)
+	if !ok {
+		return errors.New(errHubType)
+	}
+	dst.ObjectMeta = c.ObjectMeta
+	return errors.Wrapf(convertTo(&c.Spec, &c.Status, &dst.Spec, &dst.Status), errConvertFmt, c.GetName())
+}
+
+// ConvertFrom converts the v1alpha1 hub version to this NamespacedConfig.
+func (c *NamespacedConfig) ConvertFrom(hub conversion.Hub) error {
+	src, ok := hub.(*v1alpha1.NamespacedConfig)
+	if !ok {
+		return errors.New(errHubType)
+	}
+	c.ObjectMeta = src.ObjectMeta
+	return errors.Wrapf(convertFrom(&src.Spec, &src.Status, &c.Spec, &c.Status), errConvertFmt, c.GetName())
+}
+
+// convertTo converts a v1beta1 spec and status to v1alpha1. Both versions
+// share their schema apart from parts, which are converted explicitly.
+func convertTo(spec *ConfigSpec, status *ConfigStatus, dstSpec *v1alpha1.ConfigSpec, dstStatus *v1alpha1.ConfigStatus) error {
+	if err := roundTrip(spec, dstSpec); err != nil {
+		return err
+	}
+	if err := roundTrip(status, dstStatus); err != nil {
+		return err
+	}
+	dstSpec.ForProvider.Parts = nil
+	for _, p := range spec.ForProvider.Parts {
+		dp := v1alpha1.PartSpec{
+			ContentType: p.ContentType,
+			Filename:    p.Filename,
+			MergeType:   p.MergeType,
+		}
+		if err := roundTrip(p.Source, &dp.ContentFromSource); err != nil {
+			return err
+		}
+		if p.Source.Inline != nil {
+			dp.Content = p.Source.Inline.Content
+		}
+		dstSpec.ForProvider.Parts = append(dstSpec.ForProvider.Parts, dp)
+	}
+	return nil
+}
+
+// convertFrom converts a v1alpha1 spec and status to v1beta1. A v1alpha1
+// part naming several sources is converted to the source the renderer reads,
+// dropping the others.
+func convertFrom(srcSpec *v1alpha1.ConfigSpec, srcStatus *v1alpha1.ConfigStatus, spec *ConfigSpec, status *ConfigStatus) error {
+	if err := roundTrip(srcSpec, spec); err != nil {
+		return err
+	}
+	if err := roundTrip(srcStatus, status); err != nil {
+		return err
+	}
+	spec.ForProvider.Parts = nil
+	for _, sp := range srcSpec.ForProvider.Parts {
+		p := PartSpec{
+			ContentType: sp.ContentType,
+			Filename:    sp.Filename,
+			MergeType:   sp.MergeType,
+		}
+		var err error
+		switch {
+		case sp.SecretKeyRef != nil:
+			p.Source.Type = SourceTypeSecretKeyRef
+			err = roundTrip(sp.SecretKeyRef, &p.Source.SecretKeyRef)
+		case sp.ConfigMapKeyRef != nil:
+			p.Source.Type = SourceTypeConfigMapKeyRef
+			err = roundTrip(sp.ConfigMapKeyRef, &p.Source.ConfigMapKeyRef)
+		case sp.OCIArtifactRef != nil:
+			p.Source.Type = SourceTypeOCIArtifactRef
+			err = roundTrip(sp.OCIArtifactRef, &p.Source.OCIArtifactRef)
+		default:
+			p.Source.Type = SourceTypeInline
+			p.Source.Inline = &InlineSource{Content: sp.Content}
+		}
+		if err != nil {
+			return err
+		}
+		spec.ForProvider.Parts = append(spec.ForProvider.Parts, p)
+	}
+	return nil
+}
+
+// roundTrip copies the fields of src to the fields of dst with the same JSON
+// names
+func roundTrip(src, dst interface{}) error {
+	b, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, dst)
+}
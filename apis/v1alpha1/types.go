@@ -20,13 +20,194 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+
+	configv1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/config/v1alpha1"
+)
+
+// BoundaryStrategy determines the MIME boundary of Configs that do not set one
+type BoundaryStrategy string
+
+// Supported boundary strategies.
+const (
+	// BoundaryStrategyRandom uses a new random boundary for every render
+	BoundaryStrategyRandom BoundaryStrategy = "Random"
+
+	// BoundaryStrategyStatic uses the ProviderConfig default boundary
+	BoundaryStrategyStatic BoundaryStrategy = "Static"
+
+	// BoundaryStrategyContentHash derives the boundary from the parts, so
+	// identical parts always render identically
+	BoundaryStrategyContentHash BoundaryStrategy = "ContentHash"
 )
 
+// ConfigDefaults are applied to the fields a Config leaves unset
+type ConfigDefaults struct {
+	// +optional
+	Gzip *bool `json:"gzip,omitempty"`
+
+	// +optional
+	Base64Encode *bool `json:"base64Encode,omitempty"`
+
+	// BoundaryStrategy determines the boundary of Configs that do not set
+	// one. It defaults to Random.
+	// +kubebuilder:validation:Enum=Random;Static;ContentHash
+	// +optional
+	BoundaryStrategy BoundaryStrategy `json:"boundaryStrategy,omitempty"`
+
+	// Boundary is used with the Static boundary strategy
+	// +optional
+	Boundary string `json:"boundary,omitempty"`
+
+	// SizeLimit is the maximum size in bytes of a rendered document
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	SizeLimit *int64 `json:"sizeLimit,omitempty"`
+
+	// OutputKind is the kind of object written by Configs that do not set
+	// one. It defaults to ConfigMap.
+	// +kubebuilder:validation:Enum=ConfigMap;Secret
+	// +optional
+	OutputKind configv1alpha1.OutputKind `json:"outputKind,omitempty"`
+
+	// Labels are set on every written object. Labels set by a Config take
+	// precedence.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// BaselineParts are parts rendered into every Config using a ProviderConfig.
+// They are mandatory: a baseline part whose source cannot be read fails the
+// render even when marked optional, and a Config may not declare a part with
+// the filename of a baseline part.
+type BaselineParts struct {
+	// Prepend parts are rendered before the parts of the Config
+	// +optional
+	Prepend []configv1alpha1.PartSpec `json:"prepend,omitempty"`
+
+	// Append parts are rendered after the parts of the Config
+	// +optional
+	Append []configv1alpha1.PartSpec `json:"append,omitempty"`
+}
+
+// PolicyRule is a CEL expression the rendered parts of every Config using a
+// ProviderConfig must satisfy before the output is written.
+//
+// The expression is evaluated with a single variable, parts, a list with one
+// map per rendered part holding contentType (as detected by cloud-init when
+// not set), filename, mergeType, content, cloudConfig (the parsed YAML of
+// cloud-config parts, an empty map otherwise), includes (the URLs of
+// #include parts) and includeHosts (the hosts of those URLs). The cloudConfig
+// and includes of cloud-config-archive parts are those of their entries, and
+// cloud-config-jsonp parts have the values they patch in as cloudConfig.
+type PolicyRule struct {
+	// Name identifies the rule in the conditions of violating Configs
+	Name string `json:"name"`
+
+	// Expression must evaluate to true, e.g.
+	// parts.all(p, !has(p.cloudConfig.runcmd))
+	Expression string `json:"expression"`
+
+	// Message describes the violation. It defaults to the expression.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// SourcePolicy restricts the namespaces and objects the parts of Configs may
+// be read from
+type SourcePolicy struct {
+	// Namespaces lists namespaces sources may be read from
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// NamespaceSelector selects namespaces sources may be read from
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// Selector restricts sources to ConfigMaps and Secrets with matching
+	// labels
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// OutputPolicy restricts the namespaces Configs may write to
+type OutputPolicy struct {
+	// Namespaces lists namespaces outputs may be written to
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// NamespaceSelector selects namespaces outputs may be written to
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+}
+
+// ConsumerPolicy restricts the Configs that may use a ProviderConfig
+type ConsumerPolicy struct {
+	// ClusterScoped allows Configs, which are cluster scoped, to use the
+	// ProviderConfig
+	// +optional
+	ClusterScoped bool `json:"clusterScoped,omitempty"`
+
+	// Namespaces lists namespaces whose NamespacedConfigs may use the
+	// ProviderConfig
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// NamespaceSelector selects namespaces whose NamespacedConfigs may use
+	// the ProviderConfig
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+}
+
 // A ProviderConfigSpec defines the desired state of a Provider.
 type ProviderConfigSpec struct {
 	xpv1.CommonCredentialSelectors `json:",inline"`
 
-	//	xpv1.ProviderConfigSpec `json:",inline"`
+	// Defaults are merged into each Config using this ProviderConfig at
+	// render time
+	// +optional
+	Defaults ConfigDefaults `json:"defaults,omitempty"`
+
+	// BaselineParts are rendered into each Config using this ProviderConfig
+	// +optional
+	BaselineParts BaselineParts `json:"baselineParts,omitempty"`
+
+	// PolicyRules must be satisfied by each Config using this
+	// ProviderConfig before its output is written
+	// +optional
+	PolicyRules []PolicyRule `json:"policyRules,omitempty"`
+
+	// AllowedSources restricts where the parts of Configs using this
+	// ProviderConfig may be read from. A namespace is allowed when it is
+	// listed or selected. Sources are not restricted when unset. Baseline
+	// parts are exempt.
+	// +optional
+	AllowedSources *SourcePolicy `json:"allowedSources,omitempty"`
+
+	// AllowedOutputs restricts where Configs using this ProviderConfig may
+	// write. A namespace is allowed when it is listed or selected. Outputs
+	// are not restricted when unset.
+	// +optional
+	AllowedOutputs *OutputPolicy `json:"allowedOutputs,omitempty"`
+
+	// AllowedConsumers restricts the Configs that may use this
+	// ProviderConfig. Configs are not restricted when unset. A Config that
+	// may not use the ProviderConfig is not rendered, so that it cannot
+	// escape the restrictions and policy rules of another ProviderConfig.
+	// +optional
+	AllowedConsumers *ConsumerPolicy `json:"allowedConsumers,omitempty"`
+
+	// ServiceAccountRef names a ServiceAccount impersonated by Configs using
+	// this ProviderConfig that do not name their own. Its namespace defaults
+	// to the namespace of a NamespacedConfig. Baseline parts are always read
+	// with the provider's own identity.
+	// +optional
+	ServiceAccountRef *configv1alpha1.NamespacedName `json:"serviceAccountRef,omitempty"`
+
+	// AllowedServiceAccounts lists the ServiceAccounts that Configs using
+	// this ProviderConfig may impersonate. A NamespacedConfig may also
+	// impersonate ServiceAccounts in its own namespace.
+	// +optional
+	AllowedServiceAccounts []configv1alpha1.NamespacedName `json:"allowedServiceAccounts,omitempty"`
 }
 
 // A ProviderConfigStatus defines the status of a Provider.
@@ -37,7 +218,8 @@ type ProviderConfigStatus struct {
 // +kubebuilder:object:root=true
 // +kubebuilder:storageversion
 
-// A ProviderConfig configures a CloudInit 'provider', i.e. a connection to a particular
+// A ProviderConfig configures the defaults and baseline parts of the Configs
+// that reference it.
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
 // +kubebuilder:resource:scope=Cluster,categories={crossplane,provider,cloudinit}
@@ -0,0 +1,89 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/pkg/errors"
+
+	"github.com/crossplane-contrib/provider-cloudinit/internal/terraform"
+)
+
+const (
+	errListTerraform    = "cannot list Terraform configuration files"
+	errWriteDiagnostics = "cannot write diagnostics"
+	errConvertTerraform = "cannot convert Terraform configuration"
+	errConvertPartial   = "some arguments or parts could not be translated, so the Configs do not render the same documents"
+)
+
+// convertTerraform converts the cloudinit_config data sources of the
+// supplied Terraform files, or of the .tf files of the supplied directories,
+// and writes the Config manifests to w. Constructs that cannot be translated
+// are reported to diag, and fail the conversion once the manifests are
+// written.
+func convertTerraform(w, diag io.Writer, paths []string, namespace string) error {
+	files := make([]string, 0, len(paths))
+	for _, p := range paths {
+		fi, err := os.Stat(p)
+		if err != nil {
+			return errors.Wrap(err, errListTerraform)
+		}
+		if !fi.IsDir() {
+			files = append(files, p)
+			continue
+		}
+		tf, err := filepath.Glob(filepath.Join(p, "*.tf"))
+		if err != nil {
+			return errors.Wrap(err, errListTerraform)
+		}
+		files = append(files, tf...)
+	}
+
+	c := terraform.NewConverter(namespace)
+	var diags hcl.Diagnostics
+	n := 0
+	for _, f := range files {
+		cfgs, d := c.ConvertFile(f)
+		diags = append(diags, d...)
+		for _, cfg := range cfgs {
+			if n > 0 {
+				if _, err := io.WriteString(w, "---\n"); err != nil {
+					return errors.Wrap(err, errWriteManifest)
+				}
+			}
+			if err := writeManifest(w, cfg); err != nil {
+				return err
+			}
+			n++
+		}
+	}
+
+	if err := hcl.NewDiagnosticTextWriter(diag, c.Files(), 78, false).WriteDiagnostics(diags); err != nil {
+		return errors.Wrap(err, errWriteDiagnostics)
+	}
+	if diags.HasErrors() {
+		return errors.New(errConvertTerraform)
+	}
+	if len(diags) > 0 {
+		return errors.New(errConvertPartial)
+	}
+	return nil
+}
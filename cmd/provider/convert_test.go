@@ -0,0 +1,125 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestConvertTerraform(t *testing.T) {
+	const (
+		clean = `
+data "cloudinit_config" "a" {
+  part {
+    content = "#cloud-config\nhostname: a\n"
+  }
+}
+
+data "cloudinit_config" "b" {
+  gzip = false
+}
+`
+		partial = `
+data "cloudinit_config" "c" {
+  count = 2
+}
+`
+		invalid = `
+data "cloudinit_config" "d" {
+`
+	)
+
+	cases := map[string]struct {
+		files     map[string]string
+		paths     []string
+		want      string
+		wantNames []string
+		wantDiags []string
+	}{
+		"Clean": {
+			files:     map[string]string{"main.tf": clean},
+			paths:     []string{"."},
+			wantNames: []string{"a", "b"},
+		},
+		"Partial": {
+			files:     map[string]string{"main.tf": clean, "partial.tf": partial},
+			paths:     []string{"."},
+			want:      errConvertPartial,
+			wantNames: []string{"a", "b", "c"},
+			wantDiags: []string{`Cannot translate argument "count"`},
+		},
+		"Invalid": {
+			files:     map[string]string{"invalid.tf": invalid},
+			paths:     []string{"invalid.tf"},
+			want:      errConvertTerraform,
+			wantDiags: []string{"Argument or block definition required"},
+		},
+		"Missing": {
+			paths: []string{"missing.tf"},
+			want:  errListTerraform,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			for n, c := range tc.files {
+				if err := ioutil.WriteFile(filepath.Join(dir, n), []byte(c), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			paths := make([]string, len(tc.paths))
+			for i, p := range tc.paths {
+				paths[i] = filepath.Join(dir, p)
+			}
+
+			w, diag := &bytes.Buffer{}, &bytes.Buffer{}
+			err := convertTerraform(w, diag, paths, "default")
+			// the exit status of the command is non-zero when an error is returned
+			switch {
+			case tc.want == "" && err != nil:
+				t.Errorf("convertTerraform(...): want no error, got %v", err)
+			case tc.want != "" && (err == nil || !strings.HasPrefix(err.Error(), tc.want)):
+				t.Errorf("convertTerraform(...): want error %q, got %v", tc.want, err)
+			}
+
+			names := []string(nil)
+			for _, l := range strings.Split(w.String(), "\n") {
+				if strings.HasPrefix(l, "  name: ") {
+					names = append(names, strings.TrimPrefix(l, "  name: "))
+				}
+			}
+			if diff := cmp.Diff(tc.wantNames, names); diff != "" {
+				t.Errorf("convertTerraform(...): -want manifests, +got manifests:\n%s", diff)
+			}
+			for _, d := range tc.wantDiags {
+				if !strings.Contains(diag.String(), d) {
+					t.Errorf("convertTerraform(...): want diagnostic %q, got:\n%s", d, diag)
+				}
+			}
+			if len(tc.wantDiags) == 0 && diag.Len() > 0 {
+				t.Errorf("convertTerraform(...): want no diagnostics, got:\n%s", diag)
+			}
+		})
+	}
+}
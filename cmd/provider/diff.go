@@ -0,0 +1,266 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	"github.com/crossplane-contrib/provider-cloudinit/apis/config/v1alpha1"
+	apisv1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/v1alpha1"
+	clients "github.com/crossplane-contrib/provider-cloudinit/internal/clients"
+	"github.com/crossplane-contrib/provider-cloudinit/internal/cloudinit"
+	"github.com/crossplane-contrib/provider-cloudinit/internal/controller/config"
+)
+
+const (
+	errClusterClient  = "cannot create API server client"
+	errNameRequired   = "--name is required when no manifests are given"
+	errGetConfigFmt   = "cannot get Config or NamespacedConfig %q"
+	errDecodeRendered = "cannot decode rendered cloud-init data"
+	errDecodeLive     = "cannot decode live cloud-init data"
+	errDiff           = "cannot diff cloud-init data"
+)
+
+// diffConfig renders a Config and writes a unified diff of each key of its
+// output object in the cluster against the rendered data, part by part for
+// keys holding the user-data. The Config is read
+// from the supplied manifests, or from the cluster when there are none.
+// Sources missing from the manifests are read from the cluster. It returns
+// true when the data differ.
+func diffConfig(ctx context.Context, w io.Writer, paths []string, name, namespace string) (bool, error) {
+	s, err := newScheme()
+	if err != nil {
+		return false, err
+	}
+	cfg, err := ctrl.GetConfig()
+	if err != nil {
+		return false, errors.Wrap(err, errClusterClient)
+	}
+	kube, err := client.New(cfg, client.Options{Scheme: s})
+	if err != nil {
+		return false, errors.Wrap(err, errClusterClient)
+	}
+
+	var mg resource.Managed
+	var pc *apisv1alpha1.ProviderConfig
+	sources := kube
+	switch {
+	case len(paths) > 0:
+		m, err := readManifests(s, namespace, paths...)
+		if err != nil {
+			return false, err
+		}
+		if mg, err = m.config(name); err != nil {
+			return false, err
+		}
+		sources = &overlayClient{Client: kube, local: m.client(s)}
+		var ok bool
+		if pc, ok = m.providerConfig(mg); !ok {
+			pc, err = config.GetProviderConfig(ctx, kube, mg)
+		}
+		if err != nil {
+			return false, err
+		}
+	case name == "":
+		return false, errors.New(errNameRequired)
+	default:
+		if mg, err = clusterConfig(ctx, kube, name, namespace); err != nil {
+			return false, err
+		}
+		if pc, err = config.GetProviderConfig(ctx, kube, mg); err != nil {
+			return false, err
+		}
+	}
+	return diffOutput(ctx, w, kube, sources, mg, pc)
+}
+
+// diffOutput renders mg, reading its sources with the supplied client, and
+// diffs the data against its output object in the cluster
+func diffOutput(ctx context.Context, w io.Writer, kube, sources client.Client, mg resource.Managed, pc *apisv1alpha1.ProviderConfig) (bool, error) {
+	out, err := config.Render(ctx, sources, mg, pc)
+	if err != nil {
+		return false, err
+	}
+	live, err := config.Live(ctx, kube, out)
+	if err != nil {
+		return false, err
+	}
+
+	object := fmt.Sprintf("%s %s/%s", out.Kind, out.Namespace, out.Name)
+	if out.Namespace == "" {
+		object = fmt.Sprintf("%s %s", out.Kind, out.Name)
+	}
+	keys := make([]string, 0, len(out.Data)+len(live))
+	for k := range out.Data {
+		keys = append(keys, k)
+	}
+	for k := range live {
+		if _, ok := out.Data[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	differ := false
+	for _, k := range keys {
+		label := fmt.Sprintf("%s key %s", object, k)
+		rendered, ok := out.Data[k]
+		have, exists := live[k]
+		var d bool
+		switch {
+		case ok && rendered == out.UserData:
+			d, err = diffUserData(w, label, have, exists, rendered)
+		case !utf8.ValidString(have) || !utf8.ValidString(rendered):
+			d, err = writeBinaryDiff(w, label, have, rendered)
+		default:
+			d, err = writeDiff(w, label, have, rendered)
+		}
+		if err != nil {
+			return false, err
+		}
+		differ = differ || d
+	}
+	return differ, nil
+}
+
+// diffUserData writes a diff of the encoding and each part of the live and
+// rendered user-data of label, returning true when they differ
+func diffUserData(w io.Writer, label, live string, exists bool, rendered string) (bool, error) {
+	want, err := cloudinit.DecodeCloudinitConfig([]byte(rendered))
+	if err != nil {
+		return false, errors.Wrap(err, errDecodeRendered)
+	}
+	have := &cloudinit.DecodedConfig{}
+	if exists {
+		if have, err = cloudinit.DecodeCloudinitConfig([]byte(live)); err != nil {
+			return false, errors.Wrap(err, errDecodeLive)
+		}
+	}
+
+	differ, err := writeDiff(w, label+" encoding", encodingText(have, exists), encodingText(want, true))
+	if err != nil {
+		return false, err
+	}
+	for i := 0; i < len(have.Parts) || i < len(want.Parts); i++ {
+		a, b := "", ""
+		if i < len(have.Parts) {
+			a = partText(have.Parts[i])
+		}
+		if i < len(want.Parts) {
+			b = partText(want.Parts[i])
+		}
+		d, err := writeDiff(w, fmt.Sprintf("%s part %d", label, i+1), a, b)
+		if err != nil {
+			return false, err
+		}
+		differ = differ || d
+	}
+	return differ, nil
+}
+
+// encodingText describes how a cloud-init document is encoded, or returns
+// an empty string when it does not exist
+func encodingText(d *cloudinit.DecodedConfig, exists bool) string {
+	if !exists {
+		return ""
+	}
+	return fmt.Sprintf("Base64: %t\nGzip: %t\nBoundary: %s\n", d.Base64, d.Gzip, d.Boundary)
+}
+
+// writeDiff writes the unified diff of the live and rendered text of label,
+// returning true when they differ
+func writeDiff(w io.Writer, label, live, rendered string) (bool, error) {
+	d, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(live),
+		B:        splitLines(rendered),
+		FromFile: "live " + label,
+		ToFile:   "rendered " + label,
+		Context:  3,
+	})
+	if err != nil {
+		return false, errors.Wrap(err, errDiff)
+	}
+	if d == "" {
+		return false, nil
+	}
+	_, err = io.WriteString(w, d)
+	return true, errors.Wrap(err, errDiff)
+}
+
+// writeBinaryDiff reports whether the live and rendered binary data of label,
+// such as seed images, differ, as diff does for binary files
+func writeBinaryDiff(w io.Writer, label, live, rendered string) (bool, error) {
+	if live == rendered {
+		return false, nil
+	}
+	_, err := fmt.Fprintf(w, "Binary live %s and rendered %s differ\n", label, label)
+	return true, errors.Wrap(err, errDiff)
+}
+
+// splitLines splits s into lines, which an empty s has none of
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return difflib.SplitLines(s)
+}
+
+// clusterConfig gets the Config with the supplied name, or the
+// NamespacedConfig with the supplied name and namespace when there is no
+// such Config
+func clusterConfig(ctx context.Context, kube client.Client, name, namespace string) (resource.Managed, error) {
+	c := &v1alpha1.Config{}
+	err := kube.Get(ctx, types.NamespacedName{Name: name}, c)
+	if err == nil {
+		return c, nil
+	}
+	if !clients.IsErrorNotFound(err) {
+		return nil, errors.Wrapf(err, errGetConfigFmt, name)
+	}
+	nc := &v1alpha1.NamespacedConfig{}
+	if err := kube.Get(ctx, types.NamespacedName{Name: name, Namespace: namespace}, nc); err != nil {
+		return nil, errors.Wrapf(err, errGetConfigFmt, name)
+	}
+	return nc, nil
+}
+
+// An overlayClient reads objects from local manifests, falling back to the
+// cluster for objects the manifests do not hold
+type overlayClient struct {
+	client.Client
+	local client.Client
+}
+
+func (c *overlayClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	err := c.local.Get(ctx, key, obj)
+	if !clients.IsErrorNotFound(err) {
+		return err
+	}
+	return c.Client.Get(ctx, key, obj)
+}
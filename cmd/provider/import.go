@@ -0,0 +1,188 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+
+	"github.com/crossplane-contrib/provider-cloudinit/apis/config/v1alpha1"
+	"github.com/crossplane-contrib/provider-cloudinit/internal/cloudinit"
+)
+
+const (
+	errImportNameRequired = "--name is required when reading stdin"
+	errDecodePartFmt      = "cannot decode base64 content of part %d"
+	errWriteManifest      = "cannot write manifest"
+
+	defaultOutputKey = "cloud-init"
+)
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// importOptions configure the manifests importUserData writes
+type importOptions struct {
+	// name of the Config, its output and the prefix of extracted ConfigMaps
+	name string
+
+	// namespace of the output and extracted ConfigMaps, and of the Config
+	// when namespaced is true
+	namespace  string
+	namespaced bool
+
+	// key of the user-data in a ConfigMap or Secret
+	key string
+
+	// extractSize is the size in bytes from which parts are extracted into
+	// ConfigMaps. Parts are never extracted when it is zero.
+	extractSize int
+}
+
+// importUserData decodes the user-data read from path, or stdin when path is
+// empty or "-", and writes the manifest of a Config rendering it to w,
+// followed by the manifests of any ConfigMaps parts were extracted into
+func importUserData(w io.Writer, path string, o importOptions) error {
+	if o.name == "" {
+		if path == "" || path == "-" {
+			return errors.New(errImportNameRequired)
+		}
+		o.name = nameOf(path)
+	}
+	d, err := readUserData(path, o.key)
+	if err != nil {
+		return err
+	}
+
+	// namespaced configs default references to their own namespace
+	refNamespace := o.namespace
+	if o.namespaced {
+		refNamespace = ""
+	}
+
+	p := v1alpha1.ConfigParameters{
+		Gzip:         &d.Gzip,
+		Base64Encode: &d.Base64,
+		Boundary:     d.Boundary,
+	}
+	extracted := make([]runtime.Object, 0)
+	for i, part := range d.Parts {
+		content := part.Body
+		if strings.EqualFold(part.Header.Get("Content-Transfer-Encoding"), "base64") {
+			b, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(content), ""))
+			if err != nil {
+				return errors.Wrapf(err, errDecodePartFmt, i+1)
+			}
+			content = string(b)
+		}
+		ps := v1alpha1.PartSpec{
+			ContentType: part.ContentType(),
+			Filename:    part.Filename(),
+			MergeType:   part.MergeType(),
+			Content:     content,
+		}
+		if o.extractSize > 0 && len(content) >= o.extractSize {
+			cm, key := extractedConfigMap(o, part, i, content)
+			ps.Content = ""
+			ps.ConfigMapKeyRef = &v1alpha1.DataKeySelector{
+				NamespacedName: v1alpha1.NamespacedName{Name: cm.GetName(), Namespace: refNamespace},
+				Key:            key,
+			}
+			extracted = append(extracted, cm)
+		}
+		p.Parts = append(p.Parts, ps)
+	}
+
+	spec := v1alpha1.ConfigSpec{
+		ForProvider: p,
+		WriteCloudInitToRef: &v1alpha1.OutputSelector{DataKeySelector: v1alpha1.DataKeySelector{
+			NamespacedName: v1alpha1.NamespacedName{Name: o.name, Namespace: refNamespace},
+			Key:            defaultOutputKey,
+		}},
+	}
+	var cfg runtime.Object = &v1alpha1.Config{
+		TypeMeta:   metav1.TypeMeta{APIVersion: v1alpha1.SchemeGroupVersion.String(), Kind: v1alpha1.ConfigKind},
+		ObjectMeta: metav1.ObjectMeta{Name: o.name},
+		Spec:       spec,
+	}
+	if o.namespaced {
+		cfg = &v1alpha1.NamespacedConfig{
+			TypeMeta:   metav1.TypeMeta{APIVersion: v1alpha1.SchemeGroupVersion.String(), Kind: v1alpha1.NamespacedConfigKind},
+			ObjectMeta: metav1.ObjectMeta{Name: o.name, Namespace: o.namespace},
+			Spec:       spec,
+		}
+	}
+
+	for i, obj := range append([]runtime.Object{cfg}, extracted...) {
+		if i > 0 {
+			if _, err := io.WriteString(w, "---\n"); err != nil {
+				return errors.Wrap(err, errWriteManifest)
+			}
+		}
+		if err := writeManifest(w, obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractedConfigMap returns a ConfigMap holding the content of the ith part,
+// and the key it is held at
+func extractedConfigMap(o importOptions, p *cloudinit.DecodedPart, i int, content string) (*corev1.ConfigMap, string) {
+	key := partFilename(p, i)
+	if len(validation.IsConfigMapKey(key)) > 0 {
+		key = fmt.Sprintf("part-%03d", i+1)
+	}
+	return &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("%s-part-%d", o.name, i+1), Namespace: o.namespace},
+		Data:       map[string]string{key: content},
+	}, key
+}
+
+// writeManifest writes obj as YAML, omitting its status and creation time
+func writeManifest(w io.Writer, obj runtime.Object) error {
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return errors.Wrap(err, errWriteManifest)
+	}
+	delete(u, "status")
+	unstructured.RemoveNestedField(u, "metadata", "creationTimestamp")
+	b, err := yaml.Marshal(u)
+	if err != nil {
+		return errors.Wrap(err, errWriteManifest)
+	}
+	_, err = w.Write(b)
+	return errors.Wrap(err, errWriteManifest)
+}
+
+// nameOf returns an object name derived from the name of the file at path
+func nameOf(path string) string {
+	n := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(n), "-"), ".-")
+}
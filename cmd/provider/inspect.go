@@ -0,0 +1,164 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"github.com/crossplane-contrib/provider-cloudinit/internal/cloudinit"
+)
+
+const (
+	errReadUserData  = "cannot read user-data"
+	errDecodeUserFmt = "cannot decode %s key %q"
+	errNoKeyFmt      = "%s has no key %q"
+	errDecodeDoc     = "cannot decode cloud-init document"
+	errWritePart     = "cannot write part"
+)
+
+// inspectUserData decodes the user-data read from path, or stdin when path
+// is empty or "-", and describes each of its parts. Parts are written to dir
+// when it is set.
+func inspectUserData(w io.Writer, path, key, dir string) error {
+	d, err := readUserData(path, key)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Base64: %t\nGzip: %t\n", d.Base64, d.Gzip)
+	if d.Multipart {
+		fmt.Fprintf(w, "Boundary: %s\n", d.Boundary)
+	}
+	for i, p := range d.Parts {
+		fmt.Fprintf(w, "\n=== Part %d of %d: %s\n", i+1, len(d.Parts), cloudinit.EffectiveContentType(p))
+		fmt.Fprint(w, partText(p))
+
+		if dir == "" {
+			continue
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return errors.Wrap(err, errWritePart)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, partFilename(p, i)), []byte(p.Body), 0600); err != nil {
+			return errors.Wrap(err, errWritePart)
+		}
+	}
+	return nil
+}
+
+// readUserData reads and decodes the user-data held by path, or stdin when
+// path is empty or "-"
+func readUserData(path, key string) (*cloudinit.DecodedConfig, error) {
+	var b []byte
+	var err error
+	if path == "" || path == "-" {
+		b, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		b, err = ioutil.ReadFile(path) // nolint:gosec
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, errReadUserData)
+	}
+	if b, err = userDataOf(b, key); err != nil {
+		return nil, err
+	}
+	d, err := cloudinit.DecodeCloudinitConfig(b)
+	return d, errors.Wrap(err, errDecodeDoc)
+}
+
+// partText returns the sorted headers and the body of a part
+func partText(p *cloudinit.DecodedPart) string {
+	names := make([]string, 0, len(p.Header))
+	for k := range p.Header {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	b := &strings.Builder{}
+	for _, k := range names {
+		fmt.Fprintf(b, "%s: %s\n", k, strings.Join(p.Header[k], ", "))
+	}
+	fmt.Fprintf(b, "\n%s", p.Body)
+	if !strings.HasSuffix(p.Body, "\n") {
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// partFilename returns the name cloud-init stores a part as. Paths in
+// filenames are ignored.
+func partFilename(p *cloudinit.DecodedPart, i int) string {
+	if f := filepath.Base(p.Filename()); f != "." && f != "/" && f != "" {
+		return f
+	}
+	return fmt.Sprintf("part-%03d", i+1)
+}
+
+// userDataOf extracts user-data from EC2 describe-instance-attribute output,
+// or from a ConfigMap or Secret manifest. Anything else is user-data itself.
+func userDataOf(b []byte, key string) ([]byte, error) {
+	j, err := yaml.YAMLToJSON(b)
+	if err != nil {
+		return b, nil
+	}
+	doc := struct {
+		Kind       string            `json:"kind"`
+		Data       map[string]string `json:"data"`
+		StringData map[string]string `json:"stringData"`
+		BinaryData map[string]string `json:"binaryData"`
+		UserData   *struct {
+			Value string `json:"Value"`
+		} `json:"UserData"`
+	}{}
+	if err := json.Unmarshal(j, &doc); err != nil {
+		return b, nil
+	}
+
+	switch {
+	case doc.UserData != nil:
+		return []byte(doc.UserData.Value), nil
+	case doc.Kind == "ConfigMap":
+		if v, ok := doc.Data[key]; ok {
+			return []byte(v), nil
+		}
+		if v, ok := doc.BinaryData[key]; ok {
+			out, err := base64.StdEncoding.DecodeString(v)
+			return out, errors.Wrapf(err, errDecodeUserFmt, doc.Kind, key)
+		}
+		return nil, errors.Errorf(errNoKeyFmt, doc.Kind, key)
+	case doc.Kind == "Secret":
+		if v, ok := doc.StringData[key]; ok {
+			return []byte(v), nil
+		}
+		if v, ok := doc.Data[key]; ok {
+			out, err := base64.StdEncoding.DecodeString(v)
+			return out, errors.Wrapf(err, errDecodeUserFmt, doc.Kind, key)
+		}
+		return nil, errors.Errorf(errNoKeyFmt, doc.Kind, key)
+	}
+	return b, nil
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 
@@ -36,8 +37,61 @@ func main() {
 		debug          = app.Flag("debug", "Run with debug logging.").Short('d').Bool()
 		syncPeriod     = app.Flag("sync", "Controller manager sync period such as 300ms, 1.5h, or 2h45m").Short('s').Default("1h").Duration()
 		leaderElection = app.Flag("leader-election", "Use leader election for the conroller manager.").Short('l').Default("false").OverrideDefaultFromEnvar("LEADER_ELECTION").Bool()
+		webhookCertDir = app.Flag("webhook-tls-cert-dir", "Directory holding the tls.crt and tls.key the admission webhooks are served with. Webhooks are not served when unset.").String()
+		webhookPort    = app.Flag("webhook-port", "Port the admission webhooks are served on.").Default("9443").Int()
+
+		render          = app.Command("render", "Render the cloud-init data of a Config without a cluster.")
+		renderManifests = render.Arg("manifests", "Manifests of the Config or NamespacedConfig to render, and of the ConfigMaps, Secrets, Namespaces and ProviderConfig it uses.").Required().ExistingFiles()
+		renderName      = render.Flag("name", "Name of the Config to render, when the manifests hold several.").String()
+		renderNamespace = render.Flag("namespace", "Namespace of namespaced objects that do not set one.").Short('n').Default("default").String()
+		renderKey       = render.Flag("key", "Key of the output to print, such as meta-data. The user-data, or the Ignition config, is printed when unset.").String()
+
+		diff          = app.Command("diff", "Diff the cloud-init data of a Config against its output object in the cluster.")
+		diffManifests = diff.Arg("manifests", "Manifests of the Config or NamespacedConfig to render, and of the ConfigMaps, Secrets, Namespaces and ProviderConfig it uses. The Config is read from the cluster when omitted.").ExistingFiles()
+		diffName      = diff.Flag("name", "Name of the Config to diff. Required when no manifests are given.").String()
+		diffNamespace = diff.Flag("namespace", "Namespace of the NamespacedConfig to diff, and of namespaced objects that do not set one.").Short('n').Default("default").String()
+
+		inspect       = app.Command("inspect", "Decode user-data and describe each of its parts.")
+		inspectFile   = inspect.Arg("file", "User-data, a ConfigMap or Secret manifest, or EC2 describe-instance-attribute output. Read from stdin when omitted.").String()
+		inspectKey    = inspect.Flag("key", "Key of the user-data in a ConfigMap or Secret.").Default("cloud-init").String()
+		inspectOutDir = inspect.Flag("output-dir", "Directory to write each part to.").Short('o').String()
+
+		importCmd         = app.Command("import", "Write the manifest of a Config rendering existing user-data.")
+		importFile        = importCmd.Arg("file", "User-data, a ConfigMap or Secret manifest, or EC2 describe-instance-attribute output. Read from stdin when omitted.").String()
+		importName        = importCmd.Flag("name", "Name of the Config and its output. Defaults to the name of the file.").String()
+		importNamespace   = importCmd.Flag("namespace", "Namespace of the output and of extracted ConfigMaps.").Short('n').Default("default").String()
+		importNamespaced  = importCmd.Flag("namespaced", "Write a NamespacedConfig rather than a Config.").Bool()
+		importKey         = importCmd.Flag("key", "Key of the user-data in a ConfigMap or Secret.").Default("cloud-init").String()
+		importExtractSize = importCmd.Flag("extract-size", "Size in bytes from which parts are extracted into ConfigMaps. Parts are never extracted when zero.").Default("0").Int()
+
+		convert          = app.Command("convert", "Convert the cloudinit_config data sources of a Terraform configuration into Config manifests.")
+		convertPaths     = convert.Arg("paths", "Terraform configuration files, or directories of them.").Required().ExistingFilesOrDirs()
+		convertNamespace = convert.Flag("namespace", "Namespace the Configs write their output to.").Short('n').Default("default").String()
 	)
-	kingpin.MustParse(app.Parse(os.Args[1:]))
+	app.Command("start", "Start the cloudinit controllers.").Default()
+
+	switch kingpin.MustParse(app.Parse(os.Args[1:])) {
+	case render.FullCommand():
+		kingpin.FatalIfError(renderConfig(context.Background(), os.Stdout, *renderManifests, *renderName, *renderNamespace, *renderKey), "Cannot render Config")
+		return
+	case diff.FullCommand():
+		differ, err := diffConfig(context.Background(), os.Stdout, *diffManifests, *diffName, *diffNamespace)
+		kingpin.FatalIfError(err, "Cannot diff Config")
+		if differ {
+			os.Exit(1)
+		}
+		return
+	case inspect.FullCommand():
+		kingpin.FatalIfError(inspectUserData(os.Stdout, *inspectFile, *inspectKey, *inspectOutDir), "Cannot inspect user-data")
+		return
+	case importCmd.FullCommand():
+		o := importOptions{name: *importName, namespace: *importNamespace, namespaced: *importNamespaced, key: *importKey, extractSize: *importExtractSize}
+		kingpin.FatalIfError(importUserData(os.Stdout, *importFile, o), "Cannot import user-data")
+		return
+	case convert.FullCommand():
+		kingpin.FatalIfError(convertTerraform(os.Stdout, os.Stderr, *convertPaths, *convertNamespace), "Cannot convert Terraform configuration")
+		return
+	}
 
 	zl := zap.New(zap.UseDevMode(*debug))
 	log := logging.NewLogrLogger(zl.WithName("provider-cloudinit"))
@@ -57,10 +111,15 @@ func main() {
 		LeaderElection:   *leaderElection,
 		LeaderElectionID: "crossplane-leader-election-provider-cloudinit",
 		SyncPeriod:       syncPeriod,
+		Port:             *webhookPort,
+		CertDir:          *webhookCertDir,
 	})
 	kingpin.FatalIfError(err, "Cannot create controller manager")
 
 	kingpin.FatalIfError(apis.AddToScheme(mgr.GetScheme()), "Cannot add cloudinit APIs to scheme")
 	kingpin.FatalIfError(controller.Setup(mgr, log), "Cannot setup cloudinit controllers")
+	if *webhookCertDir != "" {
+		kingpin.FatalIfError(controller.SetupWebhooks(mgr, log), "Cannot setup cloudinit webhooks")
+	}
 	kingpin.FatalIfError(mgr.Start(ctrl.SetupSignalHandler()), "Cannot start controller manager")
 }
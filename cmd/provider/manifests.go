@@ -0,0 +1,175 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	"github.com/crossplane-contrib/provider-cloudinit/apis"
+	"github.com/crossplane-contrib/provider-cloudinit/apis/config/v1alpha1"
+	"github.com/crossplane-contrib/provider-cloudinit/apis/config/v1beta1"
+	apisv1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/v1alpha1"
+	"github.com/crossplane-contrib/provider-cloudinit/internal/controller/config"
+)
+
+const (
+	errReadManifest      = "cannot read manifest"
+	errDecodeManifestFmt = "cannot decode manifest %s"
+	errNoConfig          = "no Config or NamespacedConfig found in the manifests"
+	errManyConfigs       = "the manifests hold several Configs; select one with --name"
+	errConfigNotFoundFmt = "no Config or NamespacedConfig named %q found in the manifests"
+	errConvertConfig     = "cannot convert Config to v1alpha1"
+)
+
+// newScheme returns a scheme of the Kubernetes and cloudinit APIs
+func newScheme() (*runtime.Scheme, error) {
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		return nil, err
+	}
+	return s, apis.AddToScheme(s)
+}
+
+// manifests are the objects read from manifest files
+type manifests struct {
+	configs         []resource.Managed
+	providerConfigs []*apisv1alpha1.ProviderConfig
+	objects         []client.Object
+}
+
+// readManifests decodes the YAML or JSON documents of the supplied files.
+// Namespaced objects that do not set a namespace are put in namespace.
+func readManifests(s *runtime.Scheme, namespace string, paths ...string) (*manifests, error) {
+	m := &manifests{}
+	dec := serializer.NewCodecFactory(s).UniversalDeserializer()
+	for _, p := range paths {
+		b, err := ioutil.ReadFile(p) // nolint:gosec
+		if err != nil {
+			return nil, errors.Wrap(err, errReadManifest)
+		}
+		r := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(b), 4096)
+		for {
+			raw := runtime.RawExtension{}
+			if err := r.Decode(&raw); err != nil {
+				if err == io.EOF {
+					break
+				}
+				return nil, errors.Wrapf(err, errDecodeManifestFmt, p)
+			}
+			if len(bytes.TrimSpace(raw.Raw)) == 0 || bytes.Equal(bytes.TrimSpace(raw.Raw), []byte("null")) {
+				continue
+			}
+			obj, _, err := dec.Decode(raw.Raw, nil, nil)
+			if err != nil {
+				return nil, errors.Wrapf(err, errDecodeManifestFmt, p)
+			}
+			if err := m.add(obj, namespace); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return m, nil
+}
+
+func (m *manifests) add(obj runtime.Object, namespace string) error {
+	switch o := obj.(type) {
+	case *v1alpha1.Config:
+		m.configs = append(m.configs, o)
+	case *v1alpha1.NamespacedConfig:
+		if o.GetNamespace() == "" {
+			o.SetNamespace(namespace)
+		}
+		m.configs = append(m.configs, o)
+	case *v1beta1.Config:
+		hub := &v1alpha1.Config{}
+		if err := o.ConvertTo(hub); err != nil {
+			return errors.Wrap(err, errConvertConfig)
+		}
+		m.configs = append(m.configs, hub)
+	case *v1beta1.NamespacedConfig:
+		hub := &v1alpha1.NamespacedConfig{}
+		if err := o.ConvertTo(hub); err != nil {
+			return errors.Wrap(err, errConvertConfig)
+		}
+		if hub.GetNamespace() == "" {
+			hub.SetNamespace(namespace)
+		}
+		m.configs = append(m.configs, hub)
+	case *apisv1alpha1.ProviderConfig:
+		m.providerConfigs = append(m.providerConfigs, o)
+	case *corev1.Namespace:
+		m.objects = append(m.objects, o)
+	case client.Object:
+		if o.GetNamespace() == "" {
+			o.SetNamespace(namespace)
+		}
+		m.objects = append(m.objects, o)
+	}
+	return nil
+}
+
+// config returns the Config or NamespacedConfig with the supplied name, or
+// the only one when name is empty
+func (m *manifests) config(name string) (resource.Managed, error) {
+	if name == "" {
+		switch len(m.configs) {
+		case 0:
+			return nil, errors.New(errNoConfig)
+		case 1:
+			return m.configs[0], nil
+		}
+		return nil, errors.New(errManyConfigs)
+	}
+	for _, c := range m.configs {
+		if c.GetName() == name {
+			return c, nil
+		}
+	}
+	return nil, errors.Errorf(errConfigNotFoundFmt, name)
+}
+
+// providerConfig returns the ProviderConfig of mg, or an empty one when the
+// manifests do not hold it
+func (m *manifests) providerConfig(mg resource.Managed) (*apisv1alpha1.ProviderConfig, bool) {
+	name := config.ProviderConfigName(mg)
+	for _, pc := range m.providerConfigs {
+		if pc.GetName() == name {
+			return pc, true
+		}
+	}
+	pc := &apisv1alpha1.ProviderConfig{}
+	pc.SetName(name)
+	return pc, false
+}
+
+// client returns a client serving the objects of the manifests
+func (m *manifests) client(s *runtime.Scheme) client.Client {
+	return fake.NewClientBuilder().WithScheme(s).WithObjects(m.objects...).Build()
+}
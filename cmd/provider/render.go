@@ -0,0 +1,66 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"io"
+
+	"github.com/pkg/errors"
+
+	"github.com/crossplane-contrib/provider-cloudinit/apis/config/v1alpha1"
+	"github.com/crossplane-contrib/provider-cloudinit/internal/controller/config"
+)
+
+const errNoOutputKeyFmt = "the output has no key %q"
+
+// renderConfig renders a Config read from the supplied manifests, reading
+// its sources from the manifests as well, and writes the user-data, or the
+// supplied key of the output, to w. The Ignition config replaces the
+// user-data of outputs with the Ignition format.
+func renderConfig(ctx context.Context, w io.Writer, paths []string, name, namespace, key string) error {
+	s, err := newScheme()
+	if err != nil {
+		return err
+	}
+	m, err := readManifests(s, namespace, paths...)
+	if err != nil {
+		return err
+	}
+	mg, err := m.config(name)
+	if err != nil {
+		return err
+	}
+	pc, _ := m.providerConfig(mg)
+	out, err := config.Render(ctx, m.client(s), mg, pc)
+	if err != nil {
+		return err
+	}
+	if key == "" && out.Format != v1alpha1.OutputFormatIgnition {
+		_, err = io.WriteString(w, out.UserData)
+		return err
+	}
+	if key == "" {
+		key = out.Key
+	}
+	data, ok := out.Data[key]
+	if !ok {
+		return errors.Errorf(errNoOutputKeyFmt, key)
+	}
+	_, err = io.WriteString(w, data)
+	return err
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderConfig(t *testing.T) {
+	const manifest = `
+apiVersion: cloudinit.crossplane.io/v1alpha1
+kind: Config
+metadata:
+  name: node
+spec:
+  writeCloudInitToRef:
+    name: node
+    namespace: default
+    format: %s
+  forProvider:
+    boundary: MIMEBOUNDARY
+    gzip: false
+    base64Encode: false
+    parts:
+    - content: |
+        #cloud-config
+        hostname: node-1
+`
+
+	cases := map[string]struct {
+		format  string
+		key     string
+		want    string
+		wantErr bool
+	}{
+		"Keys": {
+			format: "Keys",
+			want:   "Content-Type: multipart/mixed; boundary=\"MIMEBOUNDARY\"",
+		},
+		"Ignition": {
+			format: "Ignition",
+			want:   `"ignition":{"version":"3.3.0"}`,
+		},
+		"IgnitionKey": {
+			format: "Ignition",
+			key:    "config.ign",
+			want:   `"ignition":{"version":"3.3.0"}`,
+		},
+		"MissingKey": {
+			format:  "Ignition",
+			key:     "user-data",
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := ioutil.WriteFile(path, []byte(strings.Replace(manifest, "%s", tc.format, 1)), 0600); err != nil {
+				t.Fatal(err)
+			}
+			w := &bytes.Buffer{}
+			err := renderConfig(context.Background(), w, []string{path}, "", "default", tc.key)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("renderConfig(...): want error %t, got %v", tc.wantErr, err)
+			}
+			if !strings.Contains(w.String(), tc.want) {
+				t.Errorf("renderConfig(...): want output containing %q, got:\n%s", tc.want, w)
+			}
+		})
+	}
+}
@@ -5,13 +5,20 @@ go 1.15
 require (
 	github.com/crossplane/crossplane-runtime v0.13.0
 	github.com/crossplane/crossplane-tools v0.0.0-20201007233256-88b291e145bb
+	github.com/evanphx/json-patch v4.9.0+incompatible
+	github.com/google/cel-go v0.7.3
 	github.com/google/go-cmp v0.5.2
 	github.com/google/uuid v1.2.0
+	github.com/hashicorp/hcl/v2 v2.8.2
 	github.com/mattn/go-colorable v0.1.4 // indirect
 	github.com/pkg/errors v0.9.1
+	github.com/pmezard/go-difflib v1.0.0
+	github.com/zclconf/go-cty v1.7.1
 	gopkg.in/alecthomas/kingpin.v2 v2.2.6
 	k8s.io/api v0.20.1
 	k8s.io/apimachinery v0.20.1
+	k8s.io/client-go v0.20.1
 	sigs.k8s.io/controller-runtime v0.8.0
 	sigs.k8s.io/controller-tools v0.3.0
+	sigs.k8s.io/yaml v1.2.0
 )
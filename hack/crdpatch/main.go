@@ -0,0 +1,229 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// crdpatch adds what the pinned controller-gen cannot generate to CRD
+// manifests:
+//
+//   - CEL validation rules for discriminated unions. An object with a `type`
+//     enum and a property named after each enum value (in lowerCamelCase) must
+//     set exactly the property named by its type.
+//   - Conversion for CRDs with more than one version. Versions other than the
+//     storage version are dropped, unless --conversion-webhook is set, since
+//     the conversion webhook needs a Service and CA bundle the package cannot
+//     ship, and the API server cannot serve them without it.
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+	"sigs.k8s.io/yaml"
+)
+
+const separator = "\n---\n"
+
+func main() {
+	var (
+		app       = kingpin.New(filepath.Base(os.Args[0]), "Patch generated CRD manifests.").DefaultEnvars()
+		webhook   = app.Flag("conversion-webhook", "Serve every version, converting through the webhook. Only the storage version is kept when unset.").Bool()
+		caBundle  = app.Flag("conversion-ca-bundle", "PEM file of the CA that signed the conversion webhook certificate. Required with --conversion-webhook.").ExistingFile()
+		service   = app.Flag("conversion-service", "Name of the Service fronting the conversion webhook.").Default("provider-cloudinit-webhook").String()
+		namespace = app.Flag("conversion-namespace", "Namespace of the Service fronting the conversion webhook.").Default("crossplane-system").String()
+		dir       = app.Arg("dir", "Directory of the CRD manifests.").Required().ExistingDir()
+	)
+	kingpin.MustParse(app.Parse(os.Args[1:]))
+
+	var c *conversion
+	if *webhook {
+		if *caBundle == "" {
+			kingpin.Fatalf("--conversion-ca-bundle is required with --conversion-webhook")
+		}
+		ca, err := ioutil.ReadFile(*caBundle)
+		kingpin.FatalIfError(err, "Cannot read CA bundle")
+		c = &conversion{service: *service, namespace: *namespace, caBundle: ca}
+	}
+
+	files, err := filepath.Glob(filepath.Join(*dir, "*.yaml"))
+	kingpin.FatalIfError(err, "Cannot list CRD manifests")
+	for _, f := range files {
+		kingpin.FatalIfError(patchFile(f, c), "Cannot patch %s", f)
+	}
+}
+
+// conversion locates a deployed conversion webhook
+type conversion struct {
+	service   string
+	namespace string
+	caBundle  []byte
+}
+
+func patchFile(path string, c *conversion) error {
+	b, err := ioutil.ReadFile(path) // nolint:gosec
+	if err != nil {
+		return err
+	}
+	// controller-gen prefixes each manifest with a document separator
+	prefix := ""
+	if bytes.HasPrefix(b, []byte(separator)) {
+		prefix = separator
+	}
+
+	crd := map[string]interface{}{}
+	if err := yaml.Unmarshal(b, &crd); err != nil {
+		return err
+	}
+	spec, ok := crd["spec"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	versions, _ := spec["versions"].([]interface{})
+	for _, v := range versions {
+		schema := lookup(v, "schema", "openAPIV3Schema")
+		if s, ok := schema.(map[string]interface{}); ok {
+			addUnionRules(s)
+		}
+	}
+	if len(versions) > 1 {
+		if c != nil {
+			spec["conversion"] = conversionOf(c)
+		} else {
+			spec["versions"] = storageVersions(versions)
+		}
+	}
+
+	out, err := yaml.Marshal(crd)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append([]byte(prefix), out...), 0644) // nolint:gosec
+}
+
+// storageVersions returns the storage version of versions. Without a webhook
+// the API server cannot convert, so no other version may be served, and a
+// version that is not served would only appear in the CRD.
+func storageVersions(versions []interface{}) []interface{} {
+	for _, v := range versions {
+		if vm, ok := v.(map[string]interface{}); ok && vm["storage"] == true {
+			return []interface{}{vm}
+		}
+	}
+	return versions
+}
+
+// conversionOf returns the webhook conversion of a CRD with several versions
+func conversionOf(c *conversion) map[string]interface{} {
+	return map[string]interface{}{
+		"strategy": "Webhook",
+		"webhook": map[string]interface{}{
+			"conversionReviewVersions": []interface{}{"v1"},
+			"clientConfig": map[string]interface{}{
+				"caBundle": base64.StdEncoding.EncodeToString(c.caBundle),
+				"service": map[string]interface{}{
+					"name":      c.service,
+					"namespace": c.namespace,
+					"path":      "/convert",
+				},
+			},
+		},
+	}
+}
+
+// addUnionRules walks a schema, adding validation rules to each union
+func addUnionRules(s map[string]interface{}) {
+	props, _ := s["properties"].(map[string]interface{})
+	for _, p := range props {
+		if ps, ok := p.(map[string]interface{}); ok {
+			addUnionRules(ps)
+		}
+	}
+	if items, ok := s["items"].(map[string]interface{}); ok {
+		addUnionRules(items)
+	}
+
+	members := unionMembers(props)
+	if len(members) == 0 {
+		return
+	}
+	rules := make([]interface{}, 0, len(members))
+	for _, m := range members {
+		rules = append(rules, map[string]interface{}{
+			"rule":    fmt.Sprintf("self.type == '%s' ? has(self.%s) : !has(self.%s)", m.value, m.property, m.property),
+			"message": fmt.Sprintf("%s must be set if, and only if, type is %s", m.property, m.value),
+		})
+	}
+	s["x-kubernetes-validations"] = rules
+}
+
+type member struct {
+	value    string
+	property string
+}
+
+// unionMembers returns the members of a discriminated union, or nil when the
+// properties are not one
+func unionMembers(props map[string]interface{}) []member {
+	t, ok := props["type"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	enum, _ := t["enum"].([]interface{})
+	members := make([]member, 0, len(enum))
+	for _, e := range enum {
+		v, ok := e.(string)
+		if !ok || v == "" {
+			return nil
+		}
+		p := lowerCamel(v)
+		if _, ok := props[p]; !ok {
+			return nil
+		}
+		members = append(members, member{value: v, property: p})
+	}
+	return members
+}
+
+// lowerCamel returns the JSON name of a Go name, e.g. ociArtifactRef for
+// OCIArtifactRef
+func lowerCamel(s string) string {
+	i := strings.IndexFunc(s, unicode.IsLower)
+	switch {
+	case i < 0:
+		return strings.ToLower(s)
+	case i > 1:
+		i--
+	case i == 0:
+		return s
+	}
+	return strings.ToLower(s[:i]) + s[i:]
+}
+
+func lookup(v interface{}, path ...string) interface{} {
+	for _, p := range path {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[p]
+	}
+	return v
+}
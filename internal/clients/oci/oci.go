@@ -0,0 +1,609 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package oci pulls single files out of OCI registry artifacts, such as those
+// pushed with `oras push`, using the OCI distribution HTTP API.
+package oci
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Media types understood when reading manifests and layers.
+const (
+	MediaTypeOCIManifest    = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeDockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+	MediaTypeOCILayerTar    = "application/vnd.oci.image.layer.v1.tar"
+	MediaTypeOCILayerTarGz  = "application/vnd.oci.image.layer.v1.tar+gzip"
+	MediaTypeDockerLayer    = "application/vnd.docker.image.rootfs.diff.tar.gzip"
+
+	// AnnotationTitle names the file a layer was created from (set by oras)
+	AnnotationTitle = "org.opencontainers.image.title"
+
+	defaultRegistry = "registry-1.docker.io"
+
+	// MaxBlobSize caps the size of manifests and layers that will be read
+	MaxBlobSize = 16 << 20
+
+	// DefaultCacheSize caps the bytes of manifests and layers a Client
+	// keeps between pulls
+	DefaultCacheSize = 64 << 20
+)
+
+const (
+	errParseReference = "cannot parse OCI reference"
+	errFetchManifest  = "cannot fetch OCI manifest"
+	errFetchBlob      = "cannot fetch OCI blob"
+	errDigestMismatch = "digest mismatch"
+	errNoMatchedLayer = "no layer matched the requested media type or path"
+	errTooLarge       = "OCI blob exceeds maximum size"
+	errToken          = "cannot obtain registry token"
+)
+
+// A statusError is returned when the registry responds to a request with a
+// status other than 200 OK.
+type statusError struct {
+	url    string
+	code   int
+	status string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("GET %s: %s", e.url, e.status)
+}
+
+// A notFoundError is returned when the registry does not know the manifest
+// of the requested artifact.
+type notFoundError struct {
+	error
+}
+
+func (e notFoundError) NotFound() bool { return true }
+
+// IsNotFound returns true if err indicates that the registry does not know
+// the requested artifact, as opposed to refusing or failing to serve it.
+func IsNotFound(err error) bool {
+	_, ok := errors.Cause(err).(interface{ NotFound() bool })
+	return ok
+}
+
+// Reference is a parsed OCI image reference
+type Reference struct {
+	Registry   string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseReference parses references of the form
+// [registry/]repository[:tag][@digest]. Docker Hub is assumed when no
+// registry is given.
+func ParseReference(s string) (Reference, error) {
+	ref := Reference{}
+	if s == "" {
+		return ref, errors.New(errParseReference)
+	}
+	if i := strings.Index(s, "@"); i >= 0 {
+		ref.Digest = s[i+1:]
+		s = s[:i]
+	}
+	if i := strings.LastIndex(s, ":"); i >= 0 && !strings.Contains(s[i:], "/") {
+		ref.Tag = s[i+1:]
+		s = s[:i]
+	}
+
+	parts := strings.SplitN(s, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		ref.Registry, ref.Repository = parts[0], parts[1]
+	} else {
+		ref.Registry, ref.Repository = defaultRegistry, s
+		if len(parts) == 1 {
+			ref.Repository = "library/" + s
+		}
+	}
+	if ref.Registry == "docker.io" || ref.Registry == "index.docker.io" {
+		ref.Registry = defaultRegistry
+	}
+	if ref.Repository == "" {
+		return ref, errors.New(errParseReference)
+	}
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = "latest"
+	}
+	return ref, nil
+}
+
+// String returns the reference in its canonical form
+func (r Reference) String() string {
+	s := r.Registry + "/" + r.Repository
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// Credentials are registry basic-auth credentials
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// dockerConfigJSON is the format of kubernetes.io/dockerconfigjson Secrets
+type dockerConfigJSON struct {
+	Auths map[string]struct {
+		Username string `json:"username,omitempty"`
+		Password string `json:"password,omitempty"`
+		Auth     string `json:"auth,omitempty"`
+	} `json:"auths"`
+}
+
+// CredentialsFromDockerConfigJSON returns the credentials for registry from
+// the content of a .dockerconfigjson file, or nil when none match.
+func CredentialsFromDockerConfigJSON(data []byte, registry string) (*Credentials, error) {
+	cfg := dockerConfigJSON{}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, errors.Wrap(err, "cannot parse dockerconfigjson")
+	}
+	for host, a := range cfg.Auths {
+		if normalizeHost(host) != normalizeHost(registry) {
+			continue
+		}
+		c := &Credentials{Username: a.Username, Password: a.Password}
+		if a.Auth != "" {
+			dec, err := base64.StdEncoding.DecodeString(a.Auth)
+			if err != nil {
+				return nil, errors.Wrap(err, "cannot decode dockerconfigjson auth")
+			}
+			up := strings.SplitN(string(dec), ":", 2)
+			if len(up) == 2 {
+				c.Username, c.Password = up[0], up[1]
+			}
+		}
+		return c, nil
+	}
+	return nil, nil
+}
+
+func normalizeHost(h string) string {
+	if u, err := url.Parse(h); err == nil && u.Host != "" {
+		h = u.Host
+	}
+	h = strings.TrimSuffix(h, "/")
+	switch h {
+	case "docker.io", "index.docker.io":
+		return defaultRegistry
+	}
+	return h
+}
+
+// Request selects a single file from an artifact
+type Request struct {
+	Reference Reference
+
+	// Digest, when set, pins the manifest to this digest
+	Digest string
+
+	// MediaType selects the first layer with this media type
+	MediaType string
+
+	// Path selects the layer titled with this path, or the file at this path
+	// within a tar layer
+	Path string
+
+	Credentials *Credentials
+
+	// Insecure uses plain HTTP to reach the registry
+	Insecure bool
+}
+
+type descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type manifest struct {
+	MediaType string       `json:"mediaType"`
+	Layers    []descriptor `json:"layers"`
+}
+
+// Client pulls artifact content from OCI registries. Manifests pinned by
+// digest, and layers, are content addressed and cached between pulls.
+type Client struct {
+	http  *http.Client
+	cache *blobCache
+}
+
+// NewClient returns a Client using the supplied http.Client, or
+// http.DefaultClient when nil
+func NewClient(hc *http.Client) *Client {
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	return &Client{http: hc, cache: newBlobCache(DefaultCacheSize)}
+}
+
+// Pull fetches the manifest for the request, selects a layer and returns the
+// requested content. All digests are verified.
+func (c *Client) Pull(ctx context.Context, req Request) ([]byte, error) {
+	ref := req.Reference
+	pin := req.Digest
+	if pin == "" {
+		pin = ref.Digest
+	}
+	if req.Digest != "" && ref.Digest != "" && req.Digest != ref.Digest {
+		return nil, errors.Errorf("%s: reference pins %s, digest pins %s", errDigestMismatch, ref.Digest, req.Digest)
+	}
+
+	s := &session{client: c, req: req}
+	var err error
+	tagOrDigest := ref.Tag
+	if pin != "" {
+		tagOrDigest = pin
+	}
+	mb, ok := c.cache.get(s.cacheKey(pin))
+	if !ok {
+		if mb, err = s.get(ctx, "manifests/"+tagOrDigest, MediaTypeOCIManifest+", "+MediaTypeDockerManifest); err != nil {
+			// a missing blob means a broken artifact, so only a missing
+			// manifest is reported as not found
+			if se, ok := err.(*statusError); ok && se.code == http.StatusNotFound {
+				err = notFoundError{err}
+			}
+			return nil, errors.Wrap(err, errFetchManifest)
+		}
+		if pin != "" {
+			if err := verifyDigest(mb, pin); err != nil {
+				return nil, errors.Wrap(err, errFetchManifest)
+			}
+			c.cache.add(s.cacheKey(pin), mb)
+		}
+	}
+
+	m := manifest{}
+	if err := json.Unmarshal(mb, &m); err != nil {
+		return nil, errors.Wrap(err, errFetchManifest)
+	}
+
+	layer, inTar := selectLayer(m.Layers, req.MediaType, req.Path)
+	if layer == nil {
+		return nil, errors.New(errNoMatchedLayer)
+	}
+
+	blob, ok := c.cache.get(s.cacheKey(layer.Digest))
+	if !ok {
+		if blob, err = s.get(ctx, "blobs/"+layer.Digest, ""); err != nil {
+			return nil, errors.Wrap(err, errFetchBlob)
+		}
+		if err := verifyDigest(blob, layer.Digest); err != nil {
+			return nil, errors.Wrap(err, errFetchBlob)
+		}
+		c.cache.add(s.cacheKey(layer.Digest), blob)
+	}
+	if !inTar {
+		return blob, nil
+	}
+	return extractFromTar(blob, layer.MediaType, req.Path)
+}
+
+// selectLayer picks the layer matching the request. The boolean result is
+// true when the content must be extracted from a tar layer.
+func selectLayer(layers []descriptor, mediaType, p string) (*descriptor, bool) {
+	for i := range layers {
+		l := &layers[i]
+		if mediaType != "" && l.MediaType != mediaType {
+			continue
+		}
+		if p == "" || l.Annotations[AnnotationTitle] == p {
+			return l, false
+		}
+	}
+	if p == "" {
+		return nil, false
+	}
+	for i := range layers {
+		l := &layers[i]
+		if mediaType != "" && l.MediaType != mediaType {
+			continue
+		}
+		if isTar(l.MediaType) {
+			return l, true
+		}
+	}
+	return nil, false
+}
+
+func isTar(mediaType string) bool {
+	switch mediaType {
+	case MediaTypeOCILayerTar, MediaTypeOCILayerTarGz, MediaTypeDockerLayer:
+		return true
+	}
+	return false
+}
+
+func extractFromTar(blob []byte, mediaType, p string) ([]byte, error) {
+	var r io.Reader = bytes.NewReader(blob)
+	if mediaType != MediaTypeOCILayerTar {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, errors.Wrap(err, errFetchBlob)
+		}
+		defer gz.Close() // nolint:errcheck
+		r = gz
+	}
+	want := path.Clean("/" + p)
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			return nil, errors.Errorf("%s: %s", errNoMatchedLayer, p)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, errFetchBlob)
+		}
+		if h.Typeflag != tar.TypeReg || path.Clean("/"+h.Name) != want {
+			continue
+		}
+		return readLimited(tr)
+	}
+}
+
+func verifyDigest(b []byte, digest string) error {
+	parts := strings.SplitN(digest, ":", 2)
+	if len(parts) != 2 || parts[0] != "sha256" {
+		return errors.Errorf("unsupported digest %q", digest)
+	}
+	sum := sha256.Sum256(b)
+	if got := hex.EncodeToString(sum[:]); got != parts[1] {
+		return errors.Errorf("%s: want %s, got sha256:%s", errDigestMismatch, digest, got)
+	}
+	return nil
+}
+
+func readLimited(r io.Reader) ([]byte, error) {
+	b, err := ioutil.ReadAll(io.LimitReader(r, MaxBlobSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > MaxBlobSize {
+		return nil, errors.New(errTooLarge)
+	}
+	return b, nil
+}
+
+// session carries registry authorization between requests of a single Pull
+type session struct {
+	client *Client
+	req    Request
+	token  string
+}
+
+// cacheKey returns the key content with the supplied digest is cached at.
+// Keys include the repository and credentials, so that content is only
+// served from the cache to pulls that could have fetched it.
+func (s *session) cacheKey(digest string) string {
+	if digest == "" {
+		return ""
+	}
+	h := sha256.New()
+	for _, v := range []string{s.req.Reference.Registry, s.req.Reference.Repository, digest} {
+		_, _ = h.Write([]byte(v))
+		_, _ = h.Write([]byte{0})
+	}
+	if c := s.req.Credentials; c != nil {
+		_, _ = h.Write([]byte(c.Username + "\x00" + c.Password))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (s *session) url(suffix string) string {
+	scheme := "https"
+	if s.req.Insecure {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s/v2/%s/%s", scheme, s.req.Reference.Registry, s.req.Reference.Repository, suffix)
+}
+
+func (s *session) get(ctx context.Context, suffix, accept string) ([]byte, error) {
+	resp, err := s.do(ctx, suffix, accept)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close() // nolint:errcheck
+		if err := s.authorize(ctx, challenge); err != nil {
+			return nil, err
+		}
+		if resp, err = s.do(ctx, suffix, accept); err != nil {
+			return nil, err
+		}
+	}
+	defer resp.Body.Close() // nolint:errcheck
+	if resp.StatusCode != http.StatusOK {
+		return nil, &statusError{url: s.url(suffix), code: resp.StatusCode, status: resp.Status}
+	}
+	return readLimited(resp.Body)
+}
+
+func (s *session) do(ctx context.Context, suffix, accept string) (*http.Response, error) {
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url(suffix), nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		r.Header.Set("Accept", accept)
+	}
+	switch {
+	case s.token != "":
+		r.Header.Set("Authorization", "Bearer "+s.token)
+	case s.req.Credentials != nil:
+		r.SetBasicAuth(s.req.Credentials.Username, s.req.Credentials.Password)
+	}
+	return s.client.http.Do(r)
+}
+
+// authorize implements the bearer token flow described by a
+// WWW-Authenticate challenge.
+func (s *session) authorize(ctx context.Context, challenge string) error {
+	if !strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+		return errors.Errorf("%s: unsupported challenge %q", errToken, challenge)
+	}
+	params := parseChallenge(challenge[len("bearer "):])
+	realm := params["realm"]
+	if realm == "" {
+		return errors.Errorf("%s: challenge has no realm", errToken)
+	}
+	u, err := url.Parse(realm)
+	if err != nil {
+		return errors.Wrap(err, errToken)
+	}
+	q := u.Query()
+	if svc := params["service"]; svc != "" {
+		q.Set("service", svc)
+	}
+	scope := params["scope"]
+	if scope == "" {
+		scope = "repository:" + s.req.Reference.Repository + ":pull"
+	}
+	q.Set("scope", scope)
+	u.RawQuery = q.Encode()
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return errors.Wrap(err, errToken)
+	}
+	if s.req.Credentials != nil {
+		r.SetBasicAuth(s.req.Credentials.Username, s.req.Credentials.Password)
+	}
+	resp, err := s.client.http.Do(r)
+	if err != nil {
+		return errors.Wrap(err, errToken)
+	}
+	defer resp.Body.Close() // nolint:errcheck
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("%s: %s", errToken, resp.Status)
+	}
+	tok := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, MaxBlobSize)).Decode(&tok); err != nil {
+		return errors.Wrap(err, errToken)
+	}
+	s.token = tok.Token
+	if s.token == "" {
+		s.token = tok.AccessToken
+	}
+	if s.token == "" {
+		return errors.Errorf("%s: empty token", errToken)
+	}
+	return nil
+}
+
+func parseChallenge(s string) map[string]string {
+	params := map[string]string{}
+	for s != "" {
+		s = strings.TrimLeft(s, " ,")
+		eq := strings.Index(s, "=")
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(s[:eq]))
+		s = s[eq+1:]
+		var val string
+		if strings.HasPrefix(s, `"`) {
+			end := strings.Index(s[1:], `"`)
+			if end < 0 {
+				val, s = s[1:], ""
+			} else {
+				val, s = s[1:end+1], s[end+2:]
+			}
+		} else {
+			end := strings.Index(s, ",")
+			if end < 0 {
+				val, s = s, ""
+			} else {
+				val, s = s[:end], s[end:]
+			}
+		}
+		params[key] = val
+	}
+	return params
+}
+
+// A blobCache holds content by key, evicting the oldest entries when their
+// total size exceeds its limit
+type blobCache struct {
+	mu      sync.Mutex
+	limit   int
+	size    int
+	order   []string
+	entries map[string][]byte
+}
+
+func newBlobCache(limit int) *blobCache {
+	return &blobCache{limit: limit, entries: map[string][]byte{}}
+}
+
+func (c *blobCache) get(key string) ([]byte, bool) {
+	if key == "" {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, ok := c.entries[key]
+	return b, ok
+}
+
+func (c *blobCache) add(key string, b []byte) {
+	if key == "" || len(b) > c.limit {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; ok {
+		return
+	}
+	for c.size+len(b) > c.limit {
+		c.size -= len(c.entries[c.order[0]])
+		delete(c.entries, c.order[0])
+		c.order = c.order[1:]
+	}
+	c.entries[key] = b
+	c.order = append(c.order, key)
+	c.size += len(b)
+}
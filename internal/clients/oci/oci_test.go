@@ -0,0 +1,335 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oci
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// registry is a stand-in for an OCI registry serving a single repository
+type registry struct {
+	manifests map[string][]byte
+	blobs     map[string][]byte
+
+	// token, when set, is required as a bearer token, which the token
+	// endpoint issues to the user and password
+	token    string
+	user     string
+	password string
+
+	requests int
+}
+
+func digestOf(b []byte) string {
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// A layer of an artifact. Its digest defaults to that of its content.
+type layer struct {
+	desc    descriptor
+	content []byte
+}
+
+// newRegistry returns a registry serving an artifact tagged v1 with the
+// supplied layers, and the digest of its manifest
+func newRegistry(t *testing.T, layers ...layer) (*registry, string) {
+	t.Helper()
+	r := &registry{manifests: map[string][]byte{}, blobs: map[string][]byte{}}
+	m := manifest{MediaType: MediaTypeOCIManifest}
+	for _, l := range layers {
+		d := l.desc
+		if d.Digest == "" {
+			d.Digest = digestOf(l.content)
+		}
+		d.Size = int64(len(l.content))
+		m.Layers = append(m.Layers, d)
+		r.blobs[d.Digest] = l.content
+	}
+	mb, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.manifests["v1"] = mb
+	r.manifests[digestOf(mb)] = mb
+	return r, digestOf(mb)
+}
+
+func (r *registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.requests++
+	if req.URL.Path == "/token" {
+		if u, p, ok := req.BasicAuth(); !ok || u != r.user || p != r.password {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"token": r.token})
+		return
+	}
+	if r.token != "" && req.Header.Get("Authorization") != "Bearer "+r.token {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="http://%s/token",service="test"`, req.Host))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var b []byte
+	var ok bool
+	switch p := strings.TrimPrefix(req.URL.Path, "/v2/cloudinit/parts/"); {
+	case strings.HasPrefix(p, "manifests/"):
+		b, ok = r.manifests[strings.TrimPrefix(p, "manifests/")]
+	case strings.HasPrefix(p, "blobs/"):
+		b, ok = r.blobs[strings.TrimPrefix(p, "blobs/")]
+	}
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	_, _ = w.Write(b)
+}
+
+func request(srv *httptest.Server) Request {
+	return Request{
+		Reference: Reference{Registry: strings.TrimPrefix(srv.URL, "http://"), Repository: "cloudinit/parts", Tag: "v1"},
+		Insecure:  true,
+	}
+}
+
+func tarGz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	b := &bytes.Buffer{}
+	gz := gzip.NewWriter(b)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestPullTokenAuth(t *testing.T) {
+	r, _ := newRegistry(t, layer{
+		desc:    descriptor{MediaType: "text/cloud-config", Annotations: map[string]string{AnnotationTitle: "base.yaml"}},
+		content: []byte("#cloud-config\n"),
+	})
+	r.token, r.user, r.password = "secret-token", "user", "password"
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	cases := map[string]struct {
+		creds   *Credentials
+		want    string
+		wantErr bool
+	}{
+		"ValidCredentials": {
+			creds: &Credentials{Username: "user", Password: "password"},
+			want:  "#cloud-config\n",
+		},
+		"InvalidCredentials": {
+			creds:   &Credentials{Username: "user", Password: "wrong"},
+			wantErr: true,
+		},
+		"NoCredentials": {
+			wantErr: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := request(srv)
+			req.Path = "base.yaml"
+			req.Credentials = tc.creds
+			got, err := NewClient(srv.Client()).Pull(context.Background(), req)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Pull(...): want error %t, got %v", tc.wantErr, err)
+			}
+			if diff := cmp.Diff(tc.want, string(got)); diff != "" {
+				t.Errorf("Pull(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPullDigestMismatch(t *testing.T) {
+	content := []byte("#cloud-config\n")
+	r, pin := newRegistry(t, layer{desc: descriptor{MediaType: "text/cloud-config", Digest: digestOf([]byte("other"))}, content: content})
+	// the registry serves the manifest at a digest it does not have
+	tampered := digestOf([]byte("not the manifest"))
+	r.manifests[tampered] = r.manifests["v1"]
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	cases := map[string]struct {
+		digest string
+	}{
+		"Manifest": {digest: tampered},
+		"Layer":    {digest: pin},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := request(srv)
+			req.Digest = tc.digest
+			_, err := NewClient(srv.Client()).Pull(context.Background(), req)
+			if err == nil || !strings.Contains(err.Error(), errDigestMismatch) {
+				t.Errorf("Pull(...): want %q error, got %v", errDigestMismatch, err)
+			}
+		})
+	}
+}
+
+func TestPullNotFound(t *testing.T) {
+	content := []byte("#cloud-config\n")
+	r, _ := newRegistry(t, layer{desc: descriptor{MediaType: "text/cloud-config"}, content: content})
+	r.manifests["missing-blob"] = r.manifests["v1"]
+	delete(r.blobs, digestOf(content))
+	r.user, r.password = "user", "password"
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	cases := map[string]struct {
+		tag   string
+		token string
+		want  bool
+	}{
+		"MissingManifest": {
+			tag:  "v0",
+			want: true,
+		},
+		"MissingBlob": {
+			tag:  "missing-blob",
+			want: false,
+		},
+		"Unauthorized": {
+			tag:   "v0",
+			token: "secret-token",
+			want:  false,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			r.token = tc.token
+			req := request(srv)
+			req.Reference.Tag = tc.tag
+			_, err := NewClient(srv.Client()).Pull(context.Background(), req)
+			if err == nil {
+				t.Fatal("Pull(...): want error, got nil")
+			}
+			if got := IsNotFound(err); got != tc.want {
+				t.Errorf("IsNotFound(%v): want %t, got %t", err, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestPullTarExtraction(t *testing.T) {
+	r, _ := newRegistry(t, layer{
+		desc: descriptor{MediaType: MediaTypeOCILayerTarGz},
+		content: tarGz(t, map[string]string{
+			"parts/base.yaml": "#cloud-config\n",
+			"parts/run.sh":    "#!/bin/sh\n",
+		}),
+	})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	cases := map[string]struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		"File":        {path: "parts/run.sh", want: "#!/bin/sh\n"},
+		"LeadingDot":  {path: "./parts/base.yaml", want: "#cloud-config\n"},
+		"MissingFile": {path: "parts/missing.yaml", wantErr: true},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := request(srv)
+			req.Path = tc.path
+			got, err := NewClient(srv.Client()).Pull(context.Background(), req)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Pull(...): want error %t, got %v", tc.wantErr, err)
+			}
+			if diff := cmp.Diff(tc.want, string(got)); diff != "" {
+				t.Errorf("Pull(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestPullSizeLimit(t *testing.T) {
+	r, _ := newRegistry(t, layer{desc: descriptor{MediaType: "text/cloud-config"}, content: bytes.Repeat([]byte("#"), MaxBlobSize+1)})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	_, err := NewClient(srv.Client()).Pull(context.Background(), request(srv))
+	if err == nil || !strings.Contains(err.Error(), errTooLarge) {
+		t.Errorf("Pull(...): want %q error, got %v", errTooLarge, err)
+	}
+}
+
+func TestPullCache(t *testing.T) {
+	r, pin := newRegistry(t, layer{desc: descriptor{MediaType: "text/cloud-config"}, content: []byte("#cloud-config\n")})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	cases := map[string]struct {
+		digest string
+
+		// requests made by the second pull
+		want int
+	}{
+		// tags may move, so their manifest is fetched again
+		"Tag": {want: 1},
+		// pinned manifests and their layers are served from the cache
+		"Digest": {digest: pin, want: 0},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := NewClient(srv.Client())
+			req := request(srv)
+			req.Digest = tc.digest
+			for i := 0; i < 2; i++ {
+				r.requests = 0
+				if _, err := c.Pull(context.Background(), req); err != nil {
+					t.Fatalf("Pull(...): %v", err)
+				}
+			}
+			if r.requests != tc.want {
+				t.Errorf("Pull(...): want %d requests, got %d", tc.want, r.requests)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package cloudinit
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+)
+
+// A DecodedConfig is a cloud-init document decoded by DecodeCloudinitConfig.
+// It implements CloudConfiger, so it renders back to the document it was
+// decoded from.
+type DecodedConfig struct {
+	Gzip     bool
+	Base64   bool
+	Boundary string
+
+	// Multipart is false for documents holding a single part without MIME
+	// headers, such as a bare shell script or cloud-config
+	Multipart bool
+	Parts     []*DecodedPart
+}
+
+// A DecodedPart is a part of a decoded cloud-init document
+type DecodedPart struct {
+	Header textproto.MIMEHeader
+	Body   string
+}
+
+var (
+	_ CloudConfiger = (*DecodedConfig)(nil)
+	_ PartReader    = (*DecodedPart)(nil)
+)
+
+// DecodeCloudinitConfig decodes user-data as rendered by
+// RenderCloudinitConfig. Base64 encoding and gzip compression are detected.
+func DecodeCloudinitConfig(data []byte) (*DecodedConfig, error) {
+	d := &DecodedConfig{}
+
+	if b, ok := decodeBase64(data); ok {
+		d.Base64 = true
+		data = b
+	}
+	if bytes.HasPrefix(data, []byte{0x1f, 0x8b}) {
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if data, err = ioutil.ReadAll(r); err != nil {
+			return nil, err
+		}
+		d.Gzip = true
+	}
+
+	tp := textproto.NewReader(bufio.NewReader(bytes.NewReader(data)))
+	header, err := tp.ReadMIMEHeader()
+	mediaType, params, perr := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil || perr != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		// not a MIME document; cloud-init handles it as a single part
+		d.Parts = []*DecodedPart{{Header: textproto.MIMEHeader{}, Body: string(data)}}
+		return d, nil
+	}
+
+	d.Multipart = true
+	d.Boundary = params["boundary"]
+	mr := multipart.NewReader(tp.R, d.Boundary)
+	for {
+		p, err := mr.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		body, err := ioutil.ReadAll(p)
+		if err != nil {
+			return nil, err
+		}
+		d.Parts = append(d.Parts, &DecodedPart{Header: p.Header, Body: string(body)})
+	}
+	return d, nil
+}
+
+// decodeBase64 returns the decoded data when data is base64 encoded user-data.
+// Short scripts can be valid base64 too, so the decoded data must look like
+// a gzip stream, a MIME document or a part cloud-init recognizes.
+func decodeBase64(data []byte) ([]byte, bool) {
+	s := strings.Join(strings.Fields(string(data)), "")
+	if s == "" {
+		return nil, false
+	}
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, false
+	}
+	ok := bytes.HasPrefix(b, []byte{0x1f, 0x8b}) ||
+		bytes.HasPrefix(b, []byte("Content-Type:")) ||
+		DetectContentType(string(b)) != ""
+	return b, ok
+}
+
+// UseGzipCompression is true when the document was gzip compressed
+func (d *DecodedConfig) UseGzipCompression() bool { return d.Gzip }
+
+// UseBase64Encoding is true when the document was base64 encoded
+func (d *DecodedConfig) UseBase64Encoding() bool { return d.Base64 }
+
+// Base64Boundary is the MIME boundary of the document
+func (d *DecodedConfig) Base64Boundary() string { return d.Boundary }
+
+// GetParts returns the parts of the document
+func (d *DecodedConfig) GetParts() []PartReader {
+	parts := make([]PartReader, len(d.Parts))
+	for i, p := range d.Parts {
+		parts[i] = p
+	}
+	return parts
+}
+
+// Filename is the filename of the Content-Disposition of the part
+func (p *DecodedPart) Filename() string {
+	_, params, err := mime.ParseMediaType(p.Header.Get("Content-Disposition"))
+	if err != nil {
+		return ""
+	}
+	return params["filename"]
+}
+
+// Content is the body of the part
+func (p *DecodedPart) Content() string { return p.Body }
+
+// ContentType is the Content-Type of the part
+func (p *DecodedPart) ContentType() string { return p.Header.Get("Content-Type") }
+
+// MergeType is the X-Merge-Type of the part
+func (p *DecodedPart) MergeType() string { return p.Header.Get("X-Merge-Type") }
@@ -0,0 +1,128 @@
+package cloudinit
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Content types of the parts cloud-init handles
+const (
+	ContentTypeCloudConfig        = "text/cloud-config"
+	ContentTypeCloudConfigArchive = "text/cloud-config-archive"
+	ContentTypeCloudConfigJSONP   = "text/cloud-config-jsonp"
+	ContentTypeCloudBoothook      = "text/cloud-boothook"
+	ContentTypeShellScript        = "text/x-shellscript"
+	ContentTypeIncludeURL         = "text/x-include-url"
+	ContentTypeIncludeOnceURL     = "text/x-include-once-url"
+	ContentTypePartHandler        = "text/part-handler"
+	ContentTypeUpstartJob         = "text/upstart-job"
+	ContentTypeJinja2             = "text/jinja2"
+	ContentTypePlain              = "text/plain"
+)
+
+// startsWith maps the first line prefixes cloud-init recognizes to their
+// content types. Longer prefixes are listed first.
+var startsWith = []struct {
+	prefix      string
+	contentType string
+}{
+	{"#cloud-config-archive", ContentTypeCloudConfigArchive},
+	{"#cloud-config-jsonp", ContentTypeCloudConfigJSONP},
+	{"#cloud-config", ContentTypeCloudConfig},
+	{"#cloud-boothook", ContentTypeCloudBoothook},
+	{"#include-once", ContentTypeIncludeOnceURL},
+	{"#include", ContentTypeIncludeURL},
+	{"#part-handler", ContentTypePartHandler},
+	{"#upstart-job", ContentTypeUpstartJob},
+	{"## template: jinja", ContentTypeJinja2},
+	{"#!", ContentTypeShellScript},
+}
+
+// DetectContentType returns the content type cloud-init infers from the
+// first line of content, or an empty string when it is not recognized
+func DetectContentType(content string) string {
+	for _, s := range startsWith {
+		if strings.HasPrefix(content, s.prefix) {
+			return s.contentType
+		}
+	}
+	return ""
+}
+
+// EffectiveContentType returns the content type cloud-init will handle a
+// part as. Parts without a content type, or sent as text/plain, are
+// detected from their content.
+func EffectiveContentType(p PartReader) string {
+	return ContentTypeOf(p.ContentType(), p.Content())
+}
+
+// ContentTypeOf returns the content type cloud-init will handle content
+// declared as contentType as
+func ContentTypeOf(contentType, content string) string {
+	if contentType == "" || contentType == ContentTypePlain {
+		if detected := DetectContentType(content); detected != "" {
+			return detected
+		}
+		return ContentTypePlain
+	}
+	return contentType
+}
+
+// ParseCloudConfig parses the YAML of a cloud-config part. Whole numbers are
+// returned as int64 rather than float64.
+func ParseCloudConfig(content string) (map[string]interface{}, error) {
+	j, err := yaml.YAMLToJSON([]byte(content))
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	if err := json.Unmarshal(j, &v); err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return map[string]interface{}{}, nil
+	}
+	m, ok := normalizeNumbers(v).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("cloud-config is not a mapping")
+	}
+	return m, nil
+}
+
+func normalizeNumbers(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, e := range t {
+			t[k] = normalizeNumbers(e)
+		}
+	case []interface{}:
+		for i, e := range t {
+			t[i] = normalizeNumbers(e)
+		}
+	case float64:
+		if t == math.Trunc(t) && math.Abs(t) < 1<<53 {
+			return int64(t)
+		}
+	}
+	return v
+}
+
+// IncludeURLs returns the URLs listed in an #include or #include-once part
+func IncludeURLs(content string) []string {
+	var urls []string
+	for _, line := range strings.Split(content, "\n") {
+		// like cloud-init, accept a URL on the #include line itself
+		line = strings.TrimPrefix(line, "#include-once")
+		line = strings.TrimPrefix(line, "#include")
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		urls = append(urls, line)
+	}
+	return urls
+}
@@ -0,0 +1,185 @@
+package cloudinit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// valueKind is the YAML kind a cloud-config value may have
+type valueKind int
+
+const (
+	kindBool valueKind = 1 << iota
+	kindString
+	kindInt
+	kindList
+	kindMap
+)
+
+func (k valueKind) String() string {
+	names := []string{}
+	for _, n := range []struct {
+		kind valueKind
+		name string
+	}{{kindBool, "boolean"}, {kindString, "string"}, {kindInt, "integer"}, {kindList, "list"}, {kindMap, "mapping"}} {
+		if k&n.kind != 0 {
+			names = append(names, n.name)
+		}
+	}
+	return strings.Join(names, " or ")
+}
+
+// topLevel are the kinds of the cloud-config keys handled by the modules
+// shipped with cloud-init. Keys not listed are not checked, as cloud-init
+// ignores keys no module handles.
+var topLevel = map[string]valueKind{
+	"apt":                        kindMap,
+	"bootcmd":                    kindList,
+	"ca_certs":                   kindMap,
+	"ca-certs":                   kindMap,
+	"chpasswd":                   kindMap,
+	"disable_root":               kindBool,
+	"disk_setup":                 kindMap,
+	"final_message":              kindString,
+	"fqdn":                       kindString,
+	"fs_setup":                   kindList,
+	"groups":                     kindList | kindMap | kindString,
+	"growpart":                   kindMap,
+	"hostname":                   kindString,
+	"locale":                     kindString | kindBool,
+	"manage_etc_hosts":           kindBool | kindString,
+	"mounts":                     kindList,
+	"ntp":                        kindMap,
+	"package_reboot_if_required": kindBool,
+	"package_update":             kindBool,
+	"package_upgrade":            kindBool,
+	"packages":                   kindList,
+	"phone_home":                 kindMap,
+	"power_state":                kindMap,
+	"preserve_hostname":          kindBool,
+	"resize_rootfs":              kindBool | kindString,
+	"runcmd":                     kindList,
+	"snap":                       kindMap,
+	"ssh_authorized_keys":        kindList,
+	"ssh_pwauth":                 kindBool | kindString,
+	"swap":                       kindMap,
+	"timezone":                   kindString,
+	"users":                      kindList | kindString,
+	"write_files":                kindList,
+	"yum_repos":                  kindMap,
+}
+
+// writeFile are the kinds of the keys of a write_files entry
+var writeFile = map[string]valueKind{
+	"path":        kindString,
+	"content":     kindString,
+	"source":      kindMap,
+	"owner":       kindString,
+	"permissions": kindString | kindInt,
+	"encoding":    kindString,
+	"append":      kindBool,
+	"defer":       kindBool,
+}
+
+// ValidateCloudConfig checks the content of a cloud-config part against the
+// schema of the keys cloud-init modules handle. It returns every problem
+// found, prefixed with the path of the offending key.
+func ValidateCloudConfig(content string) []error {
+	cc, err := ParseCloudConfig(content)
+	if err != nil {
+		return []error{err}
+	}
+
+	var errs []error
+	for _, k := range sortedKeys(cc) {
+		v := cc[k]
+		want, ok := topLevel[k]
+		if !ok {
+			continue
+		}
+		if !hasKind(v, want) {
+			errs = append(errs, fmt.Errorf("%s: must be of type %s", k, want))
+			continue
+		}
+		switch k {
+		case "runcmd", "bootcmd":
+			errs = append(errs, validateCommands(k, v.([]interface{}))...)
+		case "write_files":
+			errs = append(errs, validateWriteFiles(v.([]interface{}))...)
+		case "packages", "ssh_authorized_keys":
+			for i, e := range v.([]interface{}) {
+				if !hasKind(e, kindString|kindList) {
+					errs = append(errs, fmt.Errorf("%s[%d]: must be a string or list", k, i))
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// validateCommands checks runcmd and bootcmd entries, which are either a
+// shell command line or a list of arguments
+func validateCommands(key string, cmds []interface{}) []error {
+	var errs []error
+	for i, c := range cmds {
+		if l, ok := c.([]interface{}); ok {
+			for j, a := range l {
+				if !hasKind(a, kindString|kindInt|kindBool) {
+					errs = append(errs, fmt.Errorf("%s[%d][%d]: must be a string", key, i, j))
+				}
+			}
+			continue
+		}
+		if !hasKind(c, kindString) {
+			errs = append(errs, fmt.Errorf("%s[%d]: must be a string or list", key, i))
+		}
+	}
+	return errs
+}
+
+func validateWriteFiles(files []interface{}) []error {
+	var errs []error
+	for i, f := range files {
+		m, ok := f.(map[string]interface{})
+		if !ok {
+			errs = append(errs, fmt.Errorf("write_files[%d]: must be a mapping", i))
+			continue
+		}
+		if _, ok := m["path"]; !ok {
+			errs = append(errs, fmt.Errorf("write_files[%d].path: is required", i))
+		}
+		for _, k := range sortedKeys(m) {
+			if want, ok := writeFile[k]; ok && !hasKind(m[k], want) {
+				errs = append(errs, fmt.Errorf("write_files[%d].%s: must be of type %s", i, k, want))
+			}
+		}
+	}
+	return errs
+}
+
+func hasKind(v interface{}, k valueKind) bool {
+	switch v.(type) {
+	case bool:
+		return k&kindBool != 0
+	case string:
+		return k&kindString != 0
+	case int64, float64:
+		return k&kindInt != 0
+	case []interface{}:
+		return k&kindList != 0
+	case map[string]interface{}:
+		return k&kindMap != 0
+	}
+	// null values are ignored by cloud-init
+	return v == nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
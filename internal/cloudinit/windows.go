@@ -0,0 +1,164 @@
+package cloudinit
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ContentTypeCloudFormationInit is handled by cloudbase-init's heat plugin
+const ContentTypeCloudFormationInit = "text/x-cfninitdata"
+
+// windowsContentTypes are the part content types cloudbase-init handles
+var windowsContentTypes = []string{
+	ContentTypeCloudConfig,
+	ContentTypeShellScript,
+	ContentTypePartHandler,
+	ContentTypeCloudFormationInit,
+}
+
+// windowsScript matches the scripts cloudbase-init runs, in the order it
+// tries them: cmd, shebang and PowerShell scripts by their first line, and
+// EC2 style <script> and <powershell> blocks anywhere.
+var windowsScript = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)^rem cmd\s`),
+	regexp.MustCompile(`(?i)^#!`),
+	regexp.MustCompile(`(?i)^#(ps1|ps1_sysnative|ps1_x86)\s`),
+	regexp.MustCompile(`(?i)</?(script|powershell)>`),
+}
+
+// windowsBlock matches the opening tags of EC2 style script blocks
+var windowsBlock = regexp.MustCompile(`(?i)<(script|powershell)>`)
+
+// windowsModules are the kinds of the cloud-config keys handled by the
+// plugins shipped with cloudbase-init. Other keys are not supported.
+var windowsModules = map[string]valueKind{
+	"groups":       kindList,
+	"ntp":          kindMap,
+	"runcmd":       kindList,
+	"set_hostname": kindString,
+	"set_timezone": kindString,
+	"users":        kindList,
+	"write_files":  kindList,
+}
+
+// DetectWindowsContentType returns the content type cloudbase-init infers
+// from content, or an empty string when it is not recognized. Scripts it
+// runs, including PowerShell, are text/x-shellscript.
+func DetectWindowsContentType(content string) string {
+	if strings.HasPrefix(content, "#cloud-config") {
+		return ContentTypeCloudConfig
+	}
+	for _, re := range windowsScript {
+		if re.MatchString(content) {
+			return ContentTypeShellScript
+		}
+	}
+	return ""
+}
+
+// WindowsContentTypeOf returns the content type cloudbase-init will handle
+// content declared as contentType as
+func WindowsContentTypeOf(contentType, content string) string {
+	if contentType == "" || contentType == ContentTypePlain {
+		if detected := DetectWindowsContentType(content); detected != "" {
+			return detected
+		}
+		return ContentTypePlain
+	}
+	return contentType
+}
+
+// ValidateWindowsPart checks that cloudbase-init handles content declared as
+// contentType. Cloud-config may only use the keys of cloudbase-init plugins,
+// and scripts must be in a format cloudbase-init runs.
+func ValidateWindowsPart(contentType, content string) []error {
+	switch ct := WindowsContentTypeOf(contentType, content); ct {
+	case ContentTypeCloudConfig:
+		return ValidateWindowsCloudConfig(content)
+	case ContentTypeShellScript:
+		return ValidateWindowsScript(content)
+	default:
+		if !IsWindowsContentType(ct) {
+			return []error{fmt.Errorf("content type %s is not handled by cloudbase-init", ct)}
+		}
+	}
+	return nil
+}
+
+// IsWindowsContentType returns true when cloudbase-init handles parts of the
+// content type
+func IsWindowsContentType(contentType string) bool {
+	for _, ct := range windowsContentTypes {
+		if ct == contentType {
+			return true
+		}
+	}
+	return false
+}
+
+// WindowsContentTypes returns the part content types cloudbase-init handles
+func WindowsContentTypes() []string {
+	return append([]string{}, windowsContentTypes...)
+}
+
+// ValidateWindowsCloudConfig checks the content of a cloud-config part
+// against the keys cloudbase-init plugins handle. Unlike cloud-init,
+// cloudbase-init reports keys no plugin handles, so they are rejected.
+func ValidateWindowsCloudConfig(content string) []error {
+	cc, err := ParseCloudConfig(content)
+	if err != nil {
+		return []error{err}
+	}
+
+	var errs []error
+	for _, k := range sortedKeys(cc) {
+		v := cc[k]
+		want, ok := windowsModules[k]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: is not supported by cloudbase-init", k))
+			continue
+		}
+		if !hasKind(v, want) {
+			errs = append(errs, fmt.Errorf("%s: must be of type %s", k, want))
+			continue
+		}
+		switch k {
+		case "runcmd":
+			errs = append(errs, validateCommands(k, v.([]interface{}))...)
+		case "write_files":
+			errs = append(errs, validateWriteFiles(v.([]interface{}))...)
+		}
+	}
+	return errs
+}
+
+// ValidateWindowsScript checks that content is a script cloudbase-init runs,
+// and that its EC2 style blocks are closed
+func ValidateWindowsScript(content string) []error {
+	supported := false
+	for _, re := range windowsScript {
+		if re.MatchString(content) {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		line := strings.SplitN(content, "\n", 2)[0]
+		return []error{fmt.Errorf("cloudbase-init does not run scripts starting with %q", line)}
+	}
+
+	var errs []error
+	seen := map[string]bool{}
+	for _, m := range windowsBlock.FindAllStringSubmatch(content, -1) {
+		tag := strings.ToLower(m[1])
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		if !strings.Contains(strings.ToLower(content), "</"+tag+">") {
+			errs = append(errs, fmt.Errorf("<%s> block is not closed", tag))
+		}
+	}
+	return errs
+}
@@ -31,6 +31,7 @@ func Setup(mgr ctrl.Manager, l logging.Logger) error {
 	for _, setup := range []func(ctrl.Manager, logging.Logger) error{
 		providerconfig.Setup,
 		config.Setup,
+		config.SetupNamespaced,
 	} {
 		if err := setup(mgr, l); err != nil {
 			return err
@@ -38,3 +39,9 @@ func Setup(mgr ctrl.Manager, l logging.Logger) error {
 	}
 	return nil
 }
+
+// SetupWebhooks registers all CloudInit admission webhooks with the webhook
+// server of the supplied manager.
+func SetupWebhooks(mgr ctrl.Manager, l logging.Logger) error {
+	return config.SetupWebhook(mgr, l)
+}
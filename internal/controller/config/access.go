@@ -0,0 +1,152 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	v1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/config/v1alpha1"
+	apisv1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/v1alpha1"
+)
+
+const (
+	errGetNamespace = "cannot get Namespace"
+	errSelector     = "cannot parse label selector"
+)
+
+// accessDenied is returned when a Config reads or writes outside of the
+// namespaces allowed by its ProviderConfig
+type accessDenied struct {
+	message string
+}
+
+func (e *accessDenied) Error() string { return e.message }
+
+func isAccessDenied(err error) (*accessDenied, bool) {
+	d, ok := errors.Cause(err).(*accessDenied)
+	return d, ok
+}
+
+// setAccessCondition sets the NamespacesAllowed condition of the Config
+// when err denied access
+func setAccessCondition(mg resource.Managed, err error) {
+	if d, ok := isAccessDenied(err); ok {
+		mg.SetConditions(v1alpha1.NamespaceDenied(d.message))
+	}
+}
+
+// authorizeSource returns an accessDenied error when an object of the
+// supplied kind may not be read as a source. A nil policy allows all sources.
+func (e *ctrlClients) authorizeSource(ctx context.Context, sp *apisv1alpha1.SourcePolicy, kind, namespace string) error {
+	if sp == nil {
+		return nil
+	}
+	ok, err := e.namespaceAllowed(ctx, namespace, sp.Namespaces, sp.NamespaceSelector)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return &accessDenied{message: fmt.Sprintf("%s sources in namespace %q are not allowed by ProviderConfig %q", kind, namespace, e.pc.GetName())}
+	}
+	return nil
+}
+
+// authorizeSourceObject returns an accessDenied error when a source object
+// does not match the selector of the source policy.
+func (e *ctrlClients) authorizeSourceObject(sp *apisv1alpha1.SourcePolicy, kind string, o metav1.Object) error {
+	if sp == nil || sp.Selector == nil {
+		return nil
+	}
+	sel, err := metav1.LabelSelectorAsSelector(sp.Selector)
+	if err != nil {
+		return errors.Wrap(err, errSelector)
+	}
+	if !sel.Matches(labels.Set(o.GetLabels())) {
+		return &accessDenied{message: fmt.Sprintf("%s %s/%s does not match the source selector of ProviderConfig %q", kind, o.GetNamespace(), o.GetName(), e.pc.GetName())}
+	}
+	return nil
+}
+
+// authorizeConsumer returns an accessDenied error when mg may not use the
+// ProviderConfig. A nil policy allows all Configs.
+func (e *ctrlClients) authorizeConsumer(ctx context.Context, mg resource.Managed) error {
+	cp := e.pc.Spec.AllowedConsumers
+	if cp == nil {
+		return nil
+	}
+	namespace := mg.GetNamespace()
+	if namespace == "" {
+		if !cp.ClusterScoped {
+			return &accessDenied{message: fmt.Sprintf("Configs may not use ProviderConfig %q", e.pc.GetName())}
+		}
+		return nil
+	}
+	ok, err := e.namespaceAllowed(ctx, namespace, cp.Namespaces, cp.NamespaceSelector)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return &accessDenied{message: fmt.Sprintf("NamespacedConfigs in namespace %q may not use ProviderConfig %q", namespace, e.pc.GetName())}
+	}
+	return nil
+}
+
+// authorizeOutput returns an accessDenied error when the output may not be
+// written to its namespace. A nil policy allows all outputs.
+func (e *ctrlClients) authorizeOutput(ctx context.Context, t outputTarget) error {
+	op := e.pc.Spec.AllowedOutputs
+	if op == nil {
+		return nil
+	}
+	ok, err := e.namespaceAllowed(ctx, t.namespace, op.Namespaces, op.NamespaceSelector)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return &accessDenied{message: fmt.Sprintf("%s outputs in namespace %q are not allowed by ProviderConfig %q", t.kind, t.namespace, e.pc.GetName())}
+	}
+	return nil
+}
+
+func (e *ctrlClients) namespaceAllowed(ctx context.Context, namespace string, names []string, selector *metav1.LabelSelector) (bool, error) {
+	for _, n := range names {
+		if n == namespace {
+			return true, nil
+		}
+	}
+	if selector == nil {
+		return false, nil
+	}
+	sel, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false, errors.Wrap(err, errSelector)
+	}
+	ns := &corev1.Namespace{}
+	if err := e.kube.Get(ctx, types.NamespacedName{Name: namespace}, ns); err != nil {
+		return false, errors.Wrap(err, errGetNamespace)
+	}
+	return sel.Matches(labels.Set(ns.GetLabels())), nil
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	"github.com/crossplane-contrib/provider-cloudinit/internal/cloudinit"
+)
+
+const (
+	errBaselineKeyFmt   = "part %d sets cloud-config key %q of a ProviderConfig baseline part"
+	errBaselineCheckFmt = "cannot check part %d against ProviderConfig baseline parts"
+)
+
+// checkBaselineKeys rejects Config parts that would override the cloud-config
+// of prepended baseline parts. cloud-init replaces keys set by earlier parts,
+// so Config cloud-config may not set their top-level keys, nor patch them.
+// Parts are numbered from first, the position of the first Config part.
+func checkBaselineKeys(baseline, parts []cloudinit.PartReader, first int) error {
+	reserved := map[string]bool{}
+	for _, p := range baseline {
+		for _, k := range cloudConfigKeys(cloudinit.EffectiveContentType(p), p.Content()) {
+			reserved[k] = true
+		}
+	}
+	if len(reserved) == 0 {
+		return nil
+	}
+	for i, p := range parts {
+		keys, err := overriddenKeys(cloudinit.EffectiveContentType(p), p.Content())
+		if err != nil {
+			return errors.Wrapf(err, errBaselineCheckFmt, first+i)
+		}
+		for _, k := range keys {
+			if reserved[k] {
+				return errors.Errorf(errBaselineKeyFmt, first+i, k)
+			}
+		}
+	}
+	return nil
+}
+
+// cloudConfigKeys returns the top-level keys of a cloud-config part
+func cloudConfigKeys(contentType, content string) []string {
+	if contentType != cloudinit.ContentTypeCloudConfig {
+		return nil
+	}
+	cc, err := cloudinit.ParseCloudConfig(content)
+	if err != nil {
+		return nil
+	}
+	keys := make([]string, 0, len(cc))
+	for k := range cc {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// overriddenKeys returns the top-level cloud-config keys a part sets, patches,
+// or sets in the parts of an archive
+func overriddenKeys(contentType, content string) ([]string, error) {
+	switch contentType {
+	case cloudinit.ContentTypeCloudConfig:
+		if _, err := cloudinit.ParseCloudConfig(content); err != nil {
+			return nil, err
+		}
+		return cloudConfigKeys(contentType, content), nil
+	case cloudinit.ContentTypeCloudConfigJSONP:
+		ops := []struct {
+			Path string `json:"path"`
+			From string `json:"from"`
+		}{}
+		body := strings.TrimPrefix(content, "#cloud-config-jsonp")
+		if err := json.Unmarshal([]byte(body), &ops); err != nil {
+			return nil, err
+		}
+		keys := make([]string, 0, len(ops))
+		for _, op := range ops {
+			keys = append(keys, strings.SplitN(strings.TrimPrefix(op.Path, "/"), "/", 2)[0])
+		}
+		return keys, nil
+	case cloudinit.ContentTypeCloudConfigArchive:
+		entries := []struct {
+			Type    string `json:"type"`
+			Content string `json:"content"`
+		}{}
+		body := strings.TrimPrefix(content, "#cloud-config-archive")
+		if err := yaml.Unmarshal([]byte(body), &entries); err != nil {
+			return nil, err
+		}
+		var keys []string
+		for _, e := range entries {
+			k, err := overriddenKeys(cloudinit.ContentTypeOf(e.Type, e.Content), e.Content)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, k...)
+		}
+		return keys, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,40 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// The bootstrap data Secret of a Cluster API Machine.
+const (
+	clusterAPIValueKey  = "value"
+	clusterAPIFormatKey = "format"
+	clusterAPIFormat    = "cloud-config"
+
+	clusterAPISecretType       corev1.SecretType = "cluster.x-k8s.io/secret"
+	clusterAPIClusterNameLabel                   = "cluster.x-k8s.io/cluster-name"
+)
+
+// clusterAPIData returns the keys of a Cluster API bootstrap data Secret
+// holding the user-data
+func (s renderSettings) clusterAPIData(userData string) (map[string]string, error) {
+	if err := s.checkProfileOutput(); err != nil {
+		return nil, err
+	}
+	return map[string]string{clusterAPIValueKey: userData, clusterAPIFormatKey: clusterAPIFormat}, nil
+}
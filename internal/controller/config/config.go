@@ -18,11 +18,14 @@ package config
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -30,40 +33,80 @@ import (
 	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
 	"github.com/crossplane/crossplane-runtime/pkg/event"
 	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/meta"
 	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
 	"github.com/crossplane/crossplane-runtime/pkg/resource"
 
 	v1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/config/v1alpha1"
+	apisv1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/v1alpha1"
 	clients "github.com/crossplane-contrib/provider-cloudinit/internal/clients"
+	ciclient "github.com/crossplane-contrib/provider-cloudinit/internal/clients/cloudinit"
+	"github.com/crossplane-contrib/provider-cloudinit/internal/clients/oci"
 	"github.com/crossplane-contrib/provider-cloudinit/internal/cloudinit"
+	"github.com/crossplane-contrib/provider-cloudinit/internal/policy"
 )
 
 // Error strings.
 const (
-	errNotConfig           = "managed resource is not a Config"
+	errNotConfig           = "managed resource is not a Config or NamespacedConfig"
 	errGetPart             = "cannot get ConfigMap referenced as part"
-	errGetConfigMap        = "cannot get ConfigMap"
-	errCreateConfigMap     = "cannot create ConfigMap"
-	errDeleteConfigMap     = "cannot delete ConfigMap"
+	errGetOutput           = "cannot get output object"
+	errCreateOutput        = "cannot create output object"
+	errDeleteOutput        = "cannot delete output object"
 	errManagedConfigUpdate = "cannot update managed Config resource"
 	errNotRender           = "cannot render cloud-init data"
-	errUpdateConfigMap     = "cannot update ConfigMap"
+	errUpdateOutput        = "cannot update output object"
 	errOpaqueSecret        = "cannot read secrets that are not Opaque"
+	errGetPullSecret       = "cannot get Secret referenced as OCI pull secret"
+	errPullSecretType      = "OCI pull secret is not of type kubernetes.io/dockerconfigjson"
+	errPullArtifact        = "cannot pull OCI artifact referenced as part"
+	errTrackPCUsage        = "cannot track ProviderConfig usage"
+	errGetPC               = "cannot get ProviderConfig"
+	errNoOutputRef         = "writeCloudInitToRef is required"
+	errSizeLimitFmt        = "rendered cloud-init data is %d bytes, exceeding the ProviderConfig size limit of %d bytes"
+	errBaselinePart        = "cannot read ProviderConfig baseline part"
+	errBaselinePartMissing = "ProviderConfig baseline part source not found; baseline parts cannot be optional"
+	errBaselineFilenameFmt = "part filename %q is reserved by a ProviderConfig baseline part"
+	errOutputNotOwnedFmt   = "%s %s/%s was not written by this Config; label it %s=%s to let the Config manage it"
 
 	configMapKey = "cloud-init"
+
+	// DefaultProviderConfig is the ProviderConfig of Configs that do not
+	// reference one
+	DefaultProviderConfig = "default"
 )
 
+// autoPartName matches the names cloud-init gives parts without a filename
+var autoPartName = regexp.MustCompile(`^part-[0-9]{3}$`)
+
 // Setup adds a controller that reconciles
 // Config managed resources.
 func Setup(mgr ctrl.Manager, l logging.Logger) error {
-	name := managed.ControllerName(v1alpha1.ConfigGroupKind)
+	usage := resource.NewProviderConfigUsageTracker(mgr.GetClient(), &apisv1alpha1.ProviderConfigUsage{})
+	return setup(mgr, l, &v1alpha1.Config{}, v1alpha1.ConfigGroupKind, v1alpha1.ConfigGroupVersionKind, usage)
+}
+
+// SetupNamespaced adds a controller that reconciles
+// NamespacedConfig managed resources.
+func SetupNamespaced(mgr ctrl.Manager, l logging.Logger) error {
+	usage := &namespacedUsageTracker{kube: mgr.GetClient()}
+	return setup(mgr, l, &v1alpha1.NamespacedConfig{}, v1alpha1.NamespacedConfigGroupKind, v1alpha1.NamespacedConfigGroupVersionKind, usage)
+}
+
+func setup(mgr ctrl.Manager, l logging.Logger, obj client.Object, groupKind string, gvk schema.GroupVersionKind, usage resource.Tracker) error {
+	name := managed.ControllerName(groupKind)
 
 	return ctrl.NewControllerManagedBy(mgr).
 		Named(name).
-		For(&v1alpha1.Config{}).
+		For(obj).
 		Complete(managed.NewReconciler(mgr,
-			resource.ManagedKind(v1alpha1.ConfigGroupVersionKind),
-			managed.WithExternalConnecter(&ctrlConnector{kube: mgr.GetClient()}),
+			resource.ManagedKind(gvk),
+			managed.WithExternalConnecter(&ctrlConnector{
+				kube:  mgr.GetClient(),
+				usage: usage,
+				users: &impersonatingClients{config: mgr.GetConfig(), options: client.Options{Scheme: mgr.GetScheme(), Mapper: mgr.GetRESTMapper()}},
+				oci:   oci.NewClient(nil),
+			}),
 			managed.WithReferenceResolver(managed.NewAPISimpleReferenceResolver(mgr.GetClient())),
 			managed.WithConnectionPublishers(),
 			managed.WithLogger(l.WithValues("controller", name)),
@@ -71,203 +114,426 @@ func Setup(mgr ctrl.Manager, l logging.Logger) error {
 }
 
 type ctrlConnector struct {
-	kube client.Client
+	kube  client.Client
+	usage resource.Tracker
+	users *impersonatingClients
+
+	// oci is shared by every Config, so that pulled artifacts are cached
+	oci *oci.Client
 }
 
 func (c *ctrlConnector) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
-	// TODO(displague) construct some client wrapper?
-	return &ctrlClients{kube: c.kube}, nil
+	if mg.GetProviderConfigReference() == nil {
+		mg.SetProviderConfigReference(&xpv1.Reference{Name: DefaultProviderConfig})
+	}
+	// usages are removed with the resource, and must not be recreated
+	if !meta.WasDeleted(mg) {
+		if err := c.usage.Track(ctx, mg); err != nil {
+			return nil, errors.Wrap(err, errTrackPCUsage)
+		}
+	}
+
+	pc, err := GetProviderConfig(ctx, c.kube, mg)
+	if err != nil {
+		return nil, err
+	}
+	e := &ctrlClients{kube: c.kube, user: c.kube, oci: c.oci, pc: pc, usage: c.usage}
+	// a deleted Config only removes the outputs it wrote
+	if !meta.WasDeleted(mg) {
+		if err := e.authorizeConsumer(ctx, mg); err != nil {
+			setAccessCondition(mg, err)
+			return nil, err
+		}
+	}
+
+	spec, _, err := configOf(mg)
+	if err != nil {
+		return nil, err
+	}
+	sa, err := serviceAccountOf(mg, spec, pc)
+	if _, denied := isAccessDenied(err); denied && meta.WasDeleted(mg) {
+		// only outputs labelled with the UID of the Config are deleted
+		sa, err = nil, nil
+	}
+	if err != nil {
+		setAccessCondition(mg, err)
+		return nil, err
+	}
+	if sa != nil {
+		if e.user, err = c.users.get(*sa); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
 }
 
 type ctrlClients struct {
+	// kube uses the identity of the provider. user reads the sources of
+	// Config parts and writes outputs, impersonating a ServiceAccount when
+	// one is configured.
 	kube client.Client
+	user client.Client
+	oci  artifactPuller
+	pc   *apisv1alpha1.ProviderConfig
+
+	// usage tracked the ProviderConfig usage of the Config
+	usage resource.Tracker
 }
 
-func (e *ctrlClients) renderCloudInit(ctx context.Context, cr *v1alpha1.Config) (string, error) {
-	cl := clients.NewCloudInitClient(cr.Spec.ForProvider.Gzip, cr.Spec.ForProvider.Gzip, cr.Spec.ForProvider.Boundary)
-	for _, p := range cr.Spec.ForProvider.Parts {
-		content := p.Content
+// ProviderConfigName returns the name of the ProviderConfig of mg, which is
+// the default ProviderConfig when it references none
+func ProviderConfigName(mg resource.Managed) string {
+	if ref := mg.GetProviderConfigReference(); ref != nil && ref.Name != "" {
+		return ref.Name
+	}
+	return DefaultProviderConfig
+}
 
-		// TODO(displague) p.SecretKeyRef and ConfigMapKeyRef should be set exclusively
+// GetProviderConfig gets the ProviderConfig of mg. A missing default
+// ProviderConfig has no defaults, while other ProviderConfigs must exist.
+func GetProviderConfig(ctx context.Context, kube client.Client, mg resource.Managed) (*apisv1alpha1.ProviderConfig, error) {
+	name := ProviderConfigName(mg)
+	pc := &apisv1alpha1.ProviderConfig{}
+	err := kube.Get(ctx, types.NamespacedName{Name: name}, pc)
+	if clients.IsErrorNotFound(err) && name == DefaultProviderConfig {
+		pc.SetName(name)
+		return pc, nil
+	}
+	return pc, errors.Wrap(err, errGetPC)
+}
 
-		if p.SecretKeyRef != nil {
-			partSec := &corev1.Secret{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      p.ConfigMapKeyRef.Name,
-					Namespace: p.ConfigMapKeyRef.Namespace,
-				},
-			}
-			partNsn := types.NamespacedName{
-				Name:      partSec.GetName(),
-				Namespace: partSec.GetNamespace(),
-			}
-			if err := e.kube.Get(ctx, partNsn, partSec); err != nil {
-				if p.SecretKeyRef.Optional {
-					// TODO(displague) log that this optional configmap was not available
-					continue
-				}
-				return "", errors.Wrap(resource.Ignore(clients.IsErrorNotFound, err), errGetPart)
-			}
-			// TODO(displague) use a constant for Opaque
-			if partSec.Type != "Opaque" {
-				return "", errors.New(errOpaqueSecret)
-			}
-			key := p.SecretKeyRef.Key
-			if key == "" {
-				// TODO(displague) use default key, or use first key in configmap?
-				key = configMapKey
-			}
-			// TODO(displague) support binary configmap keys
-			content = string(partSec.Data[key])
-		}
+// rendering is the result of rendering a Config
+type rendering struct {
+	userData string
+	injected []v1alpha1.InjectedPart
 
-		if p.ConfigMapKeyRef != nil {
-			partCM := &corev1.ConfigMap{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      p.ConfigMapKeyRef.Name,
-					Namespace: p.ConfigMapKeyRef.Namespace,
-				},
-			}
-			partNsn := types.NamespacedName{
-				Name:      partCM.GetName(),
-				Namespace: partCM.GetNamespace(),
-			}
-			if err := e.kube.Get(ctx, partNsn, partCM); err != nil {
-				if p.ConfigMapKeyRef.Optional {
-					// TODO(displague) log that this optional configmap was not available
-					continue
-				}
-				return "", errors.Wrap(resource.Ignore(clients.IsErrorNotFound, err), errGetPart)
-			}
-			key := p.ConfigMapKeyRef.Key
-			if key == "" {
-				// TODO(displague) use default key, or use first key in configmap?
-				key = configMapKey
-			}
-			content = partCM.Data[key]
+	// data are the keys written to the output
+	data map[string]string
+}
+
+// render renders a Config, reporting whether the rendered document satisfies
+// the policy rules, and whether its sources may be read, in its conditions.
+// It is called before every write of the output, so that the conditions
+// are reported even when the output does not exist yet.
+func (e *ctrlClients) render(ctx context.Context, mg resource.Managed, spec *v1alpha1.ConfigSpec, s renderSettings) (rendering, error) {
+	r, err := e.renderCloudInit(ctx, spec, s)
+	if v, ok := policy.IsViolation(err); ok {
+		mg.SetConditions(v1alpha1.PolicyViolated(v.Rule, v.Message))
+	}
+	setAccessCondition(mg, err)
+	if err != nil {
+		return rendering{}, errors.Wrap(err, errNotRender)
+	}
+	if len(e.pc.Spec.PolicyRules) > 0 {
+		mg.SetConditions(v1alpha1.PolicySatisfied())
+	}
+	if e.pc.Spec.AllowedSources != nil || e.pc.Spec.AllowedOutputs != nil || e.pc.Spec.AllowedConsumers != nil {
+		mg.SetConditions(v1alpha1.NamespacesAllowed())
+	}
+	return r, nil
+}
+
+func (e *ctrlClients) renderCloudInit(ctx context.Context, spec *v1alpha1.ConfigSpec, s renderSettings) (rendering, error) {
+	if err := checkBaselineFilenames(spec.ForProvider.Parts, s); err != nil {
+		return rendering{}, err
+	}
+
+	r := rendering{}
+	cl := clients.NewCloudInitClient(s.gzip, s.base64Encode, "")
+	for _, p := range s.prepend {
+		if err := e.appendBaselinePart(ctx, cl, &r, p, v1alpha1.PartPositionPrepend); err != nil {
+			return rendering{}, err
+		}
+	}
+	for _, p := range spec.ForProvider.Parts {
+		content, found, err := e.partContent(ctx, e.user, p, e.pc.Spec.AllowedSources)
+		if err != nil {
+			return rendering{}, err
+		}
+		if !found {
+			// TODO(displague) log that this optional source was not available
+			continue
 		}
 
 		cl.AppendPart(content, p.Filename, p.ContentType, p.MergeType)
 	}
+	parts := cl.GetParts()
+	if err := checkBaselineKeys(parts[:len(s.prepend)], parts[len(s.prepend):], len(s.prepend)+1); err != nil {
+		return rendering{}, err
+	}
+	for _, p := range s.append {
+		if err := e.appendBaselinePart(ctx, cl, &r, p, v1alpha1.PartPositionAppend); err != nil {
+			return rendering{}, err
+		}
+	}
+	if spec.ForProvider.Target == v1alpha1.TargetCloudbaseInit {
+		if err := cloudbaseInitParts(cl); err != nil {
+			return rendering{}, err
+		}
+	}
+	if err := policy.Check(e.pc.Spec.PolicyRules, cl.GetParts()); err != nil {
+		return rendering{}, err
+	}
+	cl.ClientConfig.Base64Boundary = s.mimeBoundary(cl.GetParts())
 
-	return cloudinit.RenderCloudinitConfig(cl)
+	out, err := cloudinit.RenderCloudinitConfig(cl)
+	if err != nil {
+		return rendering{}, err
+	}
+	if s.sizeLimit > 0 && int64(len(out)) > s.sizeLimit {
+		return rendering{}, errors.Errorf(errSizeLimitFmt, len(out), s.sizeLimit)
+	}
+	r.userData = out
+	if s.output.format == v1alpha1.OutputFormatIgnition && s.output.profile == "" {
+		r.data, err = s.ignitionData(cl.GetParts())
+		return r, err
+	}
+	r.data, err = s.outputData(out, partsDigest(cl.GetParts()))
+	return r, err
 }
 
-func generateConfigMap(cr *v1alpha1.Config, want string) *corev1.ConfigMap {
-	key := cr.Spec.WriteCloudInitToRef.Key
-	if key == "" {
-		key = configMapKey
+// appendBaselinePart appends a ProviderConfig baseline part and records it as
+// injected. Baseline parts are mandatory, even when their source is optional.
+func (e *ctrlClients) appendBaselinePart(ctx context.Context, cl *ciclient.Client, r *rendering, p v1alpha1.PartSpec, pos v1alpha1.PartPosition) error {
+	content, found, err := e.partContent(ctx, e.kube, p, nil)
+	if err != nil {
+		return errors.Wrap(err, errBaselinePart)
 	}
-	return &corev1.ConfigMap{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      cr.Spec.WriteCloudInitToRef.Name,
-			Namespace: cr.Spec.WriteCloudInitToRef.Namespace,
-		},
-		Data: map[string]string{
-			key: want,
-		},
+	if !found {
+		return errors.New(errBaselinePartMissing)
 	}
+	sum := sha256.Sum256([]byte(content))
+	r.injected = append(r.injected, v1alpha1.InjectedPart{
+		ProviderConfig: e.pc.GetName(),
+		Position:       pos,
+		Index:          len(cl.GetParts()),
+		Filename:       p.Filename,
+		ContentType:    p.ContentType,
+		SHA256:         hex.EncodeToString(sum[:]),
+	})
+	cl.AppendPart(content, p.Filename, p.ContentType, p.MergeType)
+	return nil
 }
 
-func (e *ctrlClients) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
-	cr, ok := mg.(*v1alpha1.Config)
-	if !ok {
-		return managed.ExternalObservation{}, errors.New(errNotConfig)
+// checkBaselineFilenames rejects Config parts that would replace a baseline
+// part on the instance. cloud-init stores parts by filename, naming parts
+// without one part-NNN, so those names are reserved as well.
+func checkBaselineFilenames(parts []v1alpha1.PartSpec, s renderSettings) error {
+	if len(s.prepend)+len(s.append) == 0 {
+		return nil
 	}
-	cm := &corev1.ConfigMap{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      cr.Spec.WriteCloudInitToRef.Name,
-			Namespace: cr.Spec.WriteCloudInitToRef.Namespace,
-		},
+	reserved := map[string]bool{}
+	for _, p := range append(append([]v1alpha1.PartSpec{}, s.prepend...), s.append...) {
+		if p.Filename != "" {
+			reserved[p.Filename] = true
+		}
 	}
-	nsn := types.NamespacedName{
-		Name:      cm.GetName(),
-		Namespace: cm.GetNamespace(),
+	for _, p := range parts {
+		if reserved[p.Filename] || autoPartName.MatchString(p.Filename) {
+			return errors.Errorf(errBaselineFilenameFmt, p.Filename)
+		}
 	}
-	err := e.kube.Get(ctx, nsn, cm)
+	return nil
+}
 
+func (e *ctrlClients) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	spec, status, err := configOf(mg)
 	if err != nil {
-		return managed.ExternalObservation{}, errors.Wrap(resource.Ignore(clients.IsErrorNotFound, err), errGetConfigMap)
+		return managed.ExternalObservation{}, err
 	}
-
-	want, err := e.renderCloudInit(ctx, cr)
+	if meta.WasDeleted(mg) {
+		return e.observeDeleted(ctx, mg, spec)
+	}
+	s, err := newRenderSettings(spec, e.pc)
 	if err != nil {
 		return managed.ExternalObservation{}, err
 	}
-	key := cr.Spec.WriteCloudInitToRef.Key
-	if key == "" {
-		key = configMapKey
+	if err := e.authorizeOutput(ctx, s.output); err != nil {
+		setAccessCondition(mg, err)
+		return managed.ExternalObservation{}, err
+	}
+	if err := s.checkProfileOutput(); err != nil {
+		mg.SetConditions(v1alpha1.ProfileIncompatible(err.Error()))
+		return managed.ExternalObservation{}, err
+	}
+	if s.output.profile != "" {
+		mg.SetConditions(v1alpha1.ProfileCompatible())
+	}
+
+	o := outputObject(s.output)
+	nsn := types.NamespacedName{
+		Name:      o.GetName(),
+		Namespace: o.GetNamespace(),
+	}
+	if err := e.user.Get(ctx, nsn, o); err != nil {
+		return managed.ExternalObservation{}, errors.Wrap(resource.Ignore(clients.IsErrorNotFound, err), errGetOutput)
+	}
+	owned := ownsOutput(mg, o)
+	if !owned && !adoptsOutput(mg, o) {
+		return managed.ExternalObservation{}, errors.Errorf(errOutputNotOwnedFmt, s.output.kind, nsn.Namespace, nsn.Name, v1alpha1.LabelKeyOwnerUID, mg.GetUID())
 	}
-	got := cm.Data[key]
 
+	want, err := e.render(ctx, mg, spec, s)
 	if err != nil {
-		return managed.ExternalObservation{}, errors.Wrap(resource.Ignore(clients.IsErrorNotFound, err), errNotRender)
+		return managed.ExternalObservation{}, err
 	}
+	status.AtProvider.InjectedParts = want.injected
 
-	eo := managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: got == want}
+	vmUpToDate, err := e.syncVirtualMachine(ctx, s, want.data, false)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+	eo := managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: owned && outputUpToDate(s.output, o, want.data) && vmUpToDate}
 
-	currentSpec := cr.Spec.ForProvider.DeepCopy()
-	// cloudinitClient.LateInitializeSpec(&cr.Spec.ForProvider, *observed)
-	if !cmp.Equal(currentSpec, &cr.Spec.ForProvider) {
-		if err := e.kube.Update(ctx, cr); err != nil {
+	currentSpec := spec.ForProvider.DeepCopy()
+	// cloudinitClient.LateInitializeSpec(&spec.ForProvider, *observed)
+	if !cmp.Equal(currentSpec, &spec.ForProvider) {
+		if err := e.kube.Update(ctx, mg); err != nil {
 			return eo, errors.Wrap(err, errManagedConfigUpdate)
 		}
 	}
 
-	cr.SetConditions(xpv1.Available())
-	return eo, errors.Wrap(err, errManagedConfigUpdate)
+	mg.SetConditions(xpv1.Available())
+	return eo, nil
+}
+
+// observeDeleted reports whether a deleted Config still has an output to
+// delete. Its parts are not rendered and its VirtualMachine is not synced,
+// so that missing sources or a deleted VirtualMachine do not block the
+// deletion. A deleted Config only removes the outputs it wrote, wherever
+// they are.
+func (e *ctrlClients) observeDeleted(ctx context.Context, mg resource.Managed, spec *v1alpha1.ConfigSpec) (managed.ExternalObservation, error) {
+	s, err := newRenderSettings(spec, e.pc)
+	if err != nil {
+		// a Config without an output has not written anything
+		return managed.ExternalObservation{}, e.untrack(ctx, mg)
+	}
+	o := outputObject(s.output)
+	if err := e.user.Get(ctx, types.NamespacedName{Name: o.GetName(), Namespace: o.GetNamespace()}, o); err != nil {
+		if !clients.IsErrorNotFound(err) {
+			return managed.ExternalObservation{}, errors.Wrap(err, errGetOutput)
+		}
+		return managed.ExternalObservation{}, e.untrack(ctx, mg)
+	}
+	if !ownsOutput(mg, o) && !adoptsOutput(mg, o) {
+		// objects the Config did not write are left as they are
+		return managed.ExternalObservation{}, e.untrack(ctx, mg)
+	}
+	return managed.ExternalObservation{ResourceExists: true}, nil
+}
+
+// untrack deletes the ProviderConfig usage of a deleted Config once its
+// output is gone, when the usage is not garbage collected with the Config
+func (e *ctrlClients) untrack(ctx context.Context, mg resource.Managed) error {
+	u, ok := e.usage.(usageUntracker)
+	if !ok || !meta.WasDeleted(mg) {
+		return nil
+	}
+	return u.Untrack(ctx, mg)
 }
 
 func (e *ctrlClients) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
-	cr, ok := mg.(*v1alpha1.Config)
-	if !ok {
-		return managed.ExternalCreation{}, errors.New(errNotConfig)
+	spec, status, err := configOf(mg)
+	if err != nil {
+		return managed.ExternalCreation{}, err
 	}
 
-	cr.Status.SetConditions(xpv1.Creating())
+	mg.SetConditions(xpv1.Creating())
 
-	want, err := e.renderCloudInit(ctx, cr)
+	s, err := newRenderSettings(spec, e.pc)
 	if err != nil {
-		return managed.ExternalCreation{}, errors.Wrap(err, errNotRender)
+		return managed.ExternalCreation{}, err
 	}
+	if err := e.authorizeOutput(ctx, s.output); err != nil {
+		setAccessCondition(mg, err)
+		return managed.ExternalCreation{}, err
+	}
+	want, err := e.render(ctx, mg, spec, s)
+	if err != nil {
+		return managed.ExternalCreation{}, err
+	}
+
+	status.AtProvider.InjectedParts = want.injected
 
-	cm := generateConfigMap(cr, want)
-	err = e.kube.Create(ctx, cm)
-	return managed.ExternalCreation{}, errors.Wrap(err, errCreateConfigMap)
+	if err := e.user.Create(ctx, generateOutput(s.output, mg.GetUID(), want.data)); err != nil {
+		return managed.ExternalCreation{}, errors.Wrap(err, errCreateOutput)
+	}
+	_, err = e.syncVirtualMachine(ctx, s, want.data, true)
+	return managed.ExternalCreation{}, err
 }
 
 func (e *ctrlClients) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
-	cr, ok := mg.(*v1alpha1.Config)
-	if !ok {
-		return managed.ExternalUpdate{}, errors.New(errNotConfig)
+	spec, status, err := configOf(mg)
+	if err != nil {
+		return managed.ExternalUpdate{}, err
 	}
 
-	want, err := e.renderCloudInit(ctx, cr)
+	s, err := newRenderSettings(spec, e.pc)
 	if err != nil {
-		return managed.ExternalUpdate{}, errors.Wrap(err, errNotRender)
+		return managed.ExternalUpdate{}, err
+	}
+	if err := e.authorizeOutput(ctx, s.output); err != nil {
+		setAccessCondition(mg, err)
+		return managed.ExternalUpdate{}, err
+	}
+	want, err := e.render(ctx, mg, spec, s)
+	if err != nil {
+		return managed.ExternalUpdate{}, err
 	}
 
-	cm := generateConfigMap(cr, want)
-	err = e.kube.Update(ctx, cm)
-	return managed.ExternalUpdate{}, errors.Wrap(err, errUpdateConfigMap)
+	status.AtProvider.InjectedParts = want.injected
+
+	if err := e.updateOutput(ctx, generateOutput(s.output, mg.GetUID(), want.data)); err != nil {
+		return managed.ExternalUpdate{}, err
+	}
+	_, err = e.syncVirtualMachine(ctx, s, want.data, true)
+	return managed.ExternalUpdate{}, err
 
 }
 
+// updateOutput updates the output object o. The type of a Secret cannot be
+// changed, so a Secret of another type is deleted and created again.
+func (e *ctrlClients) updateOutput(ctx context.Context, o client.Object) error {
+	if want, ok := o.(*corev1.Secret); ok {
+		cur := &corev1.Secret{}
+		if err := e.user.Get(ctx, types.NamespacedName{Name: o.GetName(), Namespace: o.GetNamespace()}, cur); err != nil {
+			return errors.Wrap(err, errGetOutput)
+		}
+		if cur.Type != want.Type {
+			uid := cur.GetUID()
+			if err := e.user.Delete(ctx, cur, client.Preconditions{UID: &uid}); err != nil {
+				return errors.Wrap(err, errDeleteOutput)
+			}
+			return errors.Wrap(e.user.Create(ctx, want), errCreateOutput)
+		}
+	}
+	return errors.Wrap(e.user.Update(ctx, o), errUpdateOutput)
+}
+
 func (e *ctrlClients) Delete(ctx context.Context, mg resource.Managed) error {
-	cr, ok := mg.(*v1alpha1.Config)
-	if !ok {
-		return errors.New(errNotConfig)
+	spec, _, err := configOf(mg)
+	if err != nil {
+		return err
 	}
 
-	cr.Status.SetConditions(xpv1.Deleting())
+	mg.SetConditions(xpv1.Deleting())
 
-	nsn := &corev1.ConfigMap{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      cr.Spec.WriteCloudInitToRef.Name,
-			Namespace: cr.Spec.WriteCloudInitToRef.Namespace,
-		},
+	s, err := newRenderSettings(spec, e.pc)
+	if err != nil {
+		return err
 	}
 
-	err := e.kube.Delete(ctx, nsn)
-	return errors.Wrap(resource.Ignore(clients.IsErrorNotFound, err), errDeleteConfigMap)
+	// the output is removed even where outputs are no longer allowed, since
+	// only objects the Config wrote are deleted
+	o := outputObject(s.output)
+	if err := e.user.Get(ctx, types.NamespacedName{Name: o.GetName(), Namespace: o.GetNamespace()}, o); err != nil {
+		return errors.Wrap(resource.Ignore(clients.IsErrorNotFound, err), errGetOutput)
+	}
+	if !ownsOutput(mg, o) && !adoptsOutput(mg, o) {
+		// objects the Config did not write are left as they are
+		return nil
+	}
+	uid := o.GetUID()
+	err = e.user.Delete(ctx, o, client.Preconditions{UID: &uid})
+	return errors.Wrap(resource.Ignore(clients.IsErrorNotFound, err), errDeleteOutput)
 }
@@ -0,0 +1,252 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/reconciler/managed"
+
+	"github.com/crossplane-contrib/provider-cloudinit/apis"
+	v1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/config/v1alpha1"
+	apisv1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/v1alpha1"
+)
+
+const (
+	testConfigUID = types.UID("0b6e3a44-8d7c-4e0e-9d3c-9a1f4a2e6c11")
+	testOutput    = "user-data"
+)
+
+// newTestScheme returns a scheme of the Kubernetes and provider APIs
+func newTestScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := apis.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// newTestClients returns clients acting on a fake API server holding objs
+func newTestClients(t *testing.T, objs ...client.Object) *ctrlClients {
+	t.Helper()
+	kube := fake.NewClientBuilder().WithScheme(newTestScheme(t)).WithObjects(objs...).Build()
+	pc := &apisv1alpha1.ProviderConfig{ObjectMeta: metav1.ObjectMeta{Name: "default"}}
+	return &ctrlClients{kube: kube, user: kube, pc: pc}
+}
+
+// newTestConfig returns a Config writing an inline cloud-config part to the
+// testOutput ConfigMap in the default namespace
+func newTestConfig(c ...xpv1.Condition) *v1alpha1.Config {
+	cr := &v1alpha1.Config{
+		ObjectMeta: metav1.ObjectMeta{Name: "config", UID: testConfigUID},
+		Spec: v1alpha1.ConfigSpec{
+			ForProvider: v1alpha1.ConfigParameters{
+				Boundary: "MIMEBOUNDARY",
+				Parts:    []v1alpha1.PartSpec{{Content: "#cloud-config\nhostname: node-1\n"}},
+			},
+			WriteCloudInitToRef: &v1alpha1.OutputSelector{
+				DataKeySelector: v1alpha1.DataKeySelector{
+					NamespacedName: v1alpha1.NamespacedName{Namespace: "default", Name: testOutput},
+				},
+			},
+		},
+	}
+	cr.SetConditions(c...)
+	return cr
+}
+
+// newTestOutput returns the testOutput ConfigMap with the supplied labels
+func newTestOutput(labels map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: testOutput, Labels: labels},
+		Data:       map[string]string{configMapKey: "stale"},
+	}
+}
+
+func TestObserveOutputOwnership(t *testing.T) {
+	cases := map[string]struct {
+		conditions []xpv1.Condition
+		labels     map[string]string
+		want       managed.ExternalObservation
+		wantErr    bool
+	}{
+		"Owned": {
+			conditions: []xpv1.Condition{xpv1.Available()},
+			labels:     map[string]string{v1alpha1.LabelKeyOwnerUID: string(testConfigUID)},
+			want:       managed.ExternalObservation{ResourceExists: true},
+		},
+		"UnlabelledWrittenBeforeUpgrade": {
+			conditions: []xpv1.Condition{xpv1.Available()},
+			want:       managed.ExternalObservation{ResourceExists: true},
+		},
+		"UnlabelledNotWrittenByConfig": {
+			conditions: []xpv1.Condition{xpv1.Creating()},
+			wantErr:    true,
+		},
+		"OwnedByAnotherConfig": {
+			conditions: []xpv1.Condition{xpv1.Available()},
+			labels:     map[string]string{v1alpha1.LabelKeyOwnerUID: "another-uid"},
+			wantErr:    true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := newTestClients(t, newTestOutput(tc.labels))
+			got, err := e.Observe(context.Background(), newTestConfig(tc.conditions...))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Observe(...): want error %t, got %v", tc.wantErr, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Observe(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestAdoptOutputWrittenBeforeUpgrade(t *testing.T) {
+	ctx := context.Background()
+	e := newTestClients(t, newTestOutput(nil))
+	cr := newTestConfig(xpv1.Available())
+
+	if _, err := e.Update(ctx, cr); err != nil {
+		t.Fatalf("Update(...): %v", err)
+	}
+	o := &corev1.ConfigMap{}
+	if err := e.kube.Get(ctx, types.NamespacedName{Namespace: "default", Name: testOutput}, o); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(string(testConfigUID), o.GetLabels()[v1alpha1.LabelKeyOwnerUID]); diff != "" {
+		t.Errorf("Update(...): owner label: -want, +got:\n%s", diff)
+	}
+
+	got, err := e.Observe(ctx, cr)
+	if err != nil {
+		t.Fatalf("Observe(...): %v", err)
+	}
+	want := managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Observe(...): -want, +got:\n%s", diff)
+	}
+}
+
+func TestDeleteOutputWrittenBeforeUpgrade(t *testing.T) {
+	ctx := context.Background()
+	e := newTestClients(t, newTestOutput(nil))
+
+	if err := e.Delete(ctx, newTestConfig(xpv1.Available())); err != nil {
+		t.Fatalf("Delete(...): %v", err)
+	}
+	err := e.kube.Get(ctx, types.NamespacedName{Namespace: "default", Name: testOutput}, &corev1.ConfigMap{})
+	if err == nil {
+		t.Errorf("Delete(...): want output deleted")
+	}
+}
+
+func TestObserveDeleted(t *testing.T) {
+	owned := map[string]string{v1alpha1.LabelKeyOwnerUID: string(testConfigUID)}
+	cases := map[string]struct {
+		config      func(cr *v1alpha1.Config)
+		output      client.Object
+		want        managed.ExternalObservation
+		wantDeleted bool
+	}{
+		"MissingSource": {
+			config: func(cr *v1alpha1.Config) {
+				cr.Spec.ForProvider.Parts = []v1alpha1.PartSpec{{ContentFromSource: v1alpha1.ContentFromSource{
+					ConfigMapKeyRef: &v1alpha1.DataKeySelector{
+						NamespacedName: v1alpha1.NamespacedName{Namespace: "default", Name: "missing"},
+						Key:            "cloud-config",
+					},
+				}}}
+			},
+			output:      newTestOutput(owned),
+			want:        managed.ExternalObservation{ResourceExists: true},
+			wantDeleted: true,
+		},
+		"VirtualMachineGone": {
+			config: func(cr *v1alpha1.Config) {
+				cr.Spec.WriteCloudInitToRef.Profile = v1alpha1.OutputProfileKubeVirt
+				cr.Spec.WriteCloudInitToRef.KubeVirt = &v1alpha1.KubeVirtOutput{VirtualMachineName: "vm-1"}
+			},
+			output: &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: testOutput, Labels: owned},
+				Data:       map[string][]byte{kubeVirtUserDataKey: []byte("#cloud-config\n")},
+			},
+			want:        managed.ExternalObservation{ResourceExists: true},
+			wantDeleted: true,
+		},
+		"NotOwned": {
+			config: func(cr *v1alpha1.Config) {
+				cr.SetConditions(xpv1.Creating())
+			},
+			output: newTestOutput(nil),
+			want:   managed.ExternalObservation{},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			e := newTestClients(t, tc.output)
+			cr := newTestConfig(xpv1.Available())
+			now := metav1.Now()
+			cr.SetDeletionTimestamp(&now)
+			tc.config(cr)
+
+			got, err := e.Observe(ctx, cr)
+			if err != nil {
+				t.Fatalf("Observe(...): %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Observe(...): -want, +got:\n%s", diff)
+			}
+			if !got.ResourceExists {
+				return
+			}
+
+			if err := e.Delete(ctx, cr); err != nil {
+				t.Fatalf("Delete(...): %v", err)
+			}
+			err = e.kube.Get(ctx, types.NamespacedName{Namespace: "default", Name: testOutput}, tc.output)
+			if deleted := kerrors.IsNotFound(err); deleted != tc.wantDeleted {
+				t.Errorf("Delete(...): want output deleted %t, got %t", tc.wantDeleted, deleted)
+			}
+			got, err = e.Observe(ctx, cr)
+			if err != nil {
+				t.Fatalf("Observe(...): %v", err)
+			}
+			if diff := cmp.Diff(managed.ExternalObservation{}, got); diff != "" {
+				t.Errorf("Observe(...) after Delete(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	v1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/config/v1alpha1"
+)
+
+// Paths of the files of an OpenStack config drive, as cloud-init and Ironic
+// read them from a config-2 volume.
+const (
+	configDriveUserDataPath    = "openstack/latest/user_data"
+	configDriveMetaDataPath    = "openstack/latest/meta_data.json"
+	configDriveNetworkDataPath = "openstack/latest/network_data.json"
+
+	// configDriveVolumeID labels config drive images, as cloud-init expects
+	configDriveVolumeID = "config-2"
+
+	errConfigDriveMetaData = "cannot render config drive meta_data.json"
+)
+
+// configDriveMetaData is the meta_data.json of a config drive, as written by
+// the OpenStack metadata service
+type configDriveMetaData struct {
+	UUID             string            `json:"uuid"`
+	Name             string            `json:"name,omitempty"`
+	Hostname         string            `json:"hostname,omitempty"`
+	AvailabilityZone string            `json:"availability_zone,omitempty"`
+	ProjectID        string            `json:"project_id,omitempty"`
+	PublicKeys       map[string]string `json:"public_keys,omitempty"`
+	Meta             map[string]string `json:"meta,omitempty"`
+	LaunchIndex      int               `json:"launch_index"`
+}
+
+// configDriveSeed returns an OpenStack config drive, whose files are under
+// openstack/latest
+func configDriveSeed(cd *v1alpha1.ConfigDrive) *seed {
+	return &seed{
+		datasource:  "ConfigDrive",
+		volumeID:    configDriveVolumeID,
+		userData:    configDriveUserDataPath,
+		networkData: configDriveNetworkDataPath,
+		files: func(userData, partsDigest string) (map[string]string, error) {
+			return configDriveFiles(cd, userData, partsDigest)
+		},
+	}
+}
+
+// configDriveFiles returns the files of a config drive holding the user-data
+func configDriveFiles(cd *v1alpha1.ConfigDrive, userData, partsDigest string) (map[string]string, error) {
+	data := map[string]string{configDriveUserDataPath: userData}
+	if cd.NetworkData != "" {
+		data[configDriveNetworkDataPath] = cd.NetworkData
+	}
+
+	md := configDriveMetaData{
+		Name:             cd.MetaData.Name,
+		Hostname:         cd.MetaData.Hostname,
+		AvailabilityZone: cd.MetaData.AvailabilityZone,
+		ProjectID:        cd.MetaData.ProjectID,
+		PublicKeys:       cd.MetaData.PublicKeys,
+		Meta:             cd.MetaData.Meta,
+	}
+	if md.UUID = cd.MetaData.UUID; md.UUID == "" {
+		b, err := json.Marshal(md)
+		if err != nil {
+			return nil, errors.Wrap(err, errConfigDriveMetaData)
+		}
+		md.UUID = instanceUUID(string(b), partsDigest, data)
+	}
+	b, err := json.Marshal(md)
+	if err != nil {
+		return nil, errors.Wrap(err, errConfigDriveMetaData)
+	}
+	data[configDriveMetaDataPath] = string(b)
+	return data, nil
+}
+
+// instanceUUID derives an instance UUID from the content of a config drive,
+// so that cloud-init applies a changed drive to an existing instance. Like
+// instanceID, it hashes the parts rather than the user-data.
+func instanceUUID(metaData, partsDigest string, data map[string]string) string {
+	h := sha256.New()
+	for _, v := range []string{metaData, partsDigest, data[configDriveNetworkDataPath]} {
+		_, _ = h.Write([]byte(v))
+		_, _ = h.Write([]byte{0})
+	}
+	return uuid.NewSHA1(uuid.Nil, h.Sum(nil)).String()
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	admissionv1 "k8s.io/api/admission/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	v1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/config/v1alpha1"
+	apisv1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/v1alpha1"
+	"github.com/crossplane-contrib/provider-cloudinit/internal/cloudinit"
+)
+
+// defaulter stores the settings the renderer would otherwise choose in the
+// spec of Configs, so that they are stable and visible
+type defaulter struct {
+	kube    client.Client
+	decoder *admission.Decoder
+}
+
+// InjectDecoder injects the decoder of admission requests.
+func (d *defaulter) InjectDecoder(dec *admission.Decoder) error {
+	d.decoder = dec
+	return nil
+}
+
+// Handle defaults a Config or NamespacedConfig being created or updated.
+func (d *defaulter) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return admission.Allowed("")
+	}
+	mg, err := decode(d.decoder, req, req.Object)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	var old resource.Managed
+	if req.Operation == admissionv1.Update {
+		if old, err = decode(d.decoder, req, req.OldObject); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	}
+	pc, err := providerConfig(ctx, d.kube, mg)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	defaultSpec(specOf(mg), specOf(old), pc)
+
+	b, err := json.Marshal(mg)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, b)
+}
+
+// specOf returns the spec of a Config or NamespacedConfig, or nil
+func specOf(mg resource.Managed) *v1alpha1.ConfigSpec {
+	switch cr := mg.(type) {
+	case *v1alpha1.Config:
+		return &cr.Spec
+	case *v1alpha1.NamespacedConfig:
+		return &cr.Spec
+	}
+	return nil
+}
+
+// defaultSpec fills in the boundary and part content types the renderer would
+// use. A boundary already generated for the old spec is kept. The output key
+// is left to the renderer, since it follows the profile and format.
+func defaultSpec(spec, old *v1alpha1.ConfigSpec, pc *apisv1alpha1.ProviderConfig) {
+	if spec.ForProvider.Boundary == "" {
+		spec.ForProvider.Boundary = defaultBoundary(old, pc)
+	}
+
+	for i := range spec.ForProvider.Parts {
+		p := &spec.ForProvider.Parts[i]
+		// the content of sources is not known until rendering
+		if p.ContentType != "" || p.ConfigMapKeyRef != nil || p.SecretKeyRef != nil || p.OCIArtifactRef != nil {
+			continue
+		}
+		if spec.ForProvider.Target == v1alpha1.TargetCloudbaseInit {
+			p.ContentType = cloudinit.WindowsContentTypeOf(p.ContentType, p.Content)
+			continue
+		}
+		p.ContentType = cloudinit.ContentTypeOf(p.ContentType, p.Content)
+	}
+}
+
+// defaultBoundary returns the boundary to store in a spec that has none. It
+// is empty when the ProviderConfig derives boundaries from content.
+func defaultBoundary(old *v1alpha1.ConfigSpec, pc *apisv1alpha1.ProviderConfig) string {
+	strategy := apisv1alpha1.BoundaryStrategyRandom
+	if pc != nil && pc.Spec.Defaults.BoundaryStrategy != "" {
+		strategy = pc.Spec.Defaults.BoundaryStrategy
+	}
+	switch strategy {
+	case apisv1alpha1.BoundaryStrategyStatic:
+		return pc.Spec.Defaults.Boundary
+	case apisv1alpha1.BoundaryStrategyContentHash:
+		return ""
+	}
+	if old != nil && old.ForProvider.Boundary != "" {
+		return old.ForProvider.Boundary
+	}
+	return uuid.NewString()
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// Keys of the guestinfo values cloud-init's VMware datasource reads from the
+// extraConfig of a virtual machine.
+const (
+	guestInfoUserDataKey   = "guestinfo.userdata"
+	guestInfoMetaDataKey   = "guestinfo.metadata"
+	guestInfoVendorDataKey = "guestinfo.vendordata"
+
+	// guestInfoEncodingSuffix suffixes the key of the encoding of a value
+	guestInfoEncodingSuffix = ".encoding"
+
+	errGuestInfoMetaData = "cannot render guestinfo metadata"
+)
+
+// guestInfoData returns the guestinfo values of a NoCloud seed. The network
+// configuration of the seed is embedded in the metadata. Values are encoded
+// as the user-data was rendered.
+func (s renderSettings) guestInfoData(files map[string]string) (map[string]string, error) {
+	md := map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(files[noCloudMetaDataKey]), &md); err != nil {
+		return nil, errors.Wrap(err, errGuestInfoMetaData)
+	}
+	if nc, ok := files[noCloudNetworkConfigKey]; ok {
+		var network interface{}
+		if err := yaml.Unmarshal([]byte(nc), &network); err != nil {
+			return nil, errors.Wrap(err, errGuestInfoMetaData)
+		}
+		md["network"] = network
+	}
+	b, err := yaml.Marshal(md)
+	if err != nil {
+		return nil, errors.Wrap(err, errGuestInfoMetaData)
+	}
+
+	encoding := ""
+	switch {
+	case s.gzip:
+		encoding = "gzip+base64"
+	case s.base64Encode:
+		encoding = "base64"
+	}
+	data := map[string]string{}
+	set := func(key, value string, encoded bool) error {
+		if !encoded {
+			var err error
+			if value, err = s.encodeGuestInfo(value); err != nil {
+				return err
+			}
+		}
+		data[key] = value
+		if encoding != "" {
+			data[key+guestInfoEncodingSuffix] = encoding
+		}
+		return nil
+	}
+	if err := set(guestInfoUserDataKey, files[noCloudUserDataKey], true); err != nil {
+		return nil, err
+	}
+	if err := set(guestInfoMetaDataKey, string(b), false); err != nil {
+		return nil, errors.Wrap(err, errGuestInfoMetaData)
+	}
+	if vd, ok := files[noCloudVendorDataKey]; ok {
+		if err := set(guestInfoVendorDataKey, vd, false); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// encodeGuestInfo encodes a guestinfo value as the user-data was rendered
+func (s renderSettings) encodeGuestInfo(value string) (string, error) {
+	b := []byte(value)
+	if s.gzip {
+		buf := &bytes.Buffer{}
+		zw := gzip.NewWriter(buf)
+		if _, err := zw.Write(b); err != nil {
+			return "", err
+		}
+		if err := zw.Close(); err != nil {
+			return "", err
+		}
+		b = buf.Bytes()
+	}
+	if s.base64Encode {
+		return base64.StdEncoding.EncodeToString(b), nil
+	}
+	return string(b), nil
+}
@@ -0,0 +1,35 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"github.com/crossplane-contrib/provider-cloudinit/internal/cloudinit"
+	"github.com/crossplane-contrib/provider-cloudinit/internal/ignition"
+)
+
+// ignitionKey is the default output key of Ignition configs
+const ignitionKey = "config.ign"
+
+// ignitionData returns the output key holding the Ignition config parts
+// translate to. The rendered user-data is not written.
+func (s renderSettings) ignitionData(parts []cloudinit.PartReader) (map[string]string, error) {
+	b, err := ignition.Translate(parts)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{s.output.key: string(b)}, nil
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	v1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/config/v1alpha1"
+	apisv1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/v1alpha1"
+)
+
+const (
+	errImpersonate = "cannot create client impersonating ServiceAccount"
+)
+
+// serviceAccountOf returns the ServiceAccount to impersonate on behalf of a
+// Config spec, or nil to use the identity of the provider. A Config may only
+// name a ServiceAccount its ProviderConfig allows, or, for a NamespacedConfig,
+// one in its own namespace.
+func serviceAccountOf(mg resource.Managed, spec *v1alpha1.ConfigSpec, pc *apisv1alpha1.ProviderConfig) (*v1alpha1.NamespacedName, error) {
+	if sa := spec.ForProvider.ServiceAccountRef; sa != nil {
+		// namespaces of the spec are required or confined by configOf
+		if sa.Namespace != "" && sa.Namespace == mg.GetNamespace() {
+			return sa, nil
+		}
+		for _, a := range pc.Spec.AllowedServiceAccounts {
+			if a == *sa {
+				return sa, nil
+			}
+		}
+		return nil, &accessDenied{message: fmt.Sprintf("ServiceAccount %s/%s may not be impersonated by Configs using ProviderConfig %q", sa.Namespace, sa.Name, pc.GetName())}
+	}
+	if pc.Spec.ServiceAccountRef == nil {
+		return nil, nil
+	}
+	sa := *pc.Spec.ServiceAccountRef
+	if sa.Namespace == "" {
+		sa.Namespace = mg.GetNamespace()
+	}
+	if sa.Namespace == "" {
+		return nil, errors.Errorf(errNamespaceRequiredFmt, "ServiceAccount", sa.Name)
+	}
+	return &sa, nil
+}
+
+// impersonatingClients caches a client per impersonated ServiceAccount
+type impersonatingClients struct {
+	config  *rest.Config
+	options client.Options
+
+	mu      sync.Mutex
+	clients map[v1alpha1.NamespacedName]client.Client
+}
+
+// get returns the client impersonating the supplied ServiceAccount
+func (c *impersonatingClients) get(sa v1alpha1.NamespacedName) (client.Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if kc, ok := c.clients[sa]; ok {
+		return kc, nil
+	}
+	kc, err := impersonatingClient(c.config, c.options, sa)
+	if err != nil {
+		return nil, err
+	}
+	if c.clients == nil {
+		c.clients = map[v1alpha1.NamespacedName]client.Client{}
+	}
+	c.clients[sa] = kc
+	return kc, nil
+}
+
+// impersonatingClient returns a client that authenticates as the supplied
+// ServiceAccount, using the credentials of cfg to impersonate it
+func impersonatingClient(cfg *rest.Config, o client.Options, sa v1alpha1.NamespacedName) (client.Client, error) {
+	ic := rest.CopyConfig(cfg)
+	ic.Impersonate = rest.ImpersonationConfig{
+		UserName: fmt.Sprintf("system:serviceaccount:%s:%s", sa.Namespace, sa.Name),
+		Groups:   []string{"system:serviceaccounts", "system:serviceaccounts:" + sa.Namespace, "system:authenticated"},
+	}
+	c, err := client.New(ic, o)
+	return c, errors.Wrap(err, errImpersonate)
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/config/v1alpha1"
+	apisv1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/v1alpha1"
+)
+
+// apiServer is a stand-in for the API server that records the impersonation
+// headers of each request and serves an empty ConfigMap
+type apiServer struct {
+	mu     sync.Mutex
+	users  []string
+	groups [][]string
+}
+
+func (s *apiServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.users = append(s.users, r.Header.Get("Impersonate-User"))
+	s.groups = append(s.groups, r.Header["Impersonate-Group"])
+	s.mu.Unlock()
+
+	cm := &corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Name: "parts", Namespace: "team-a"},
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(cm)
+}
+
+func newImpersonatingClients(t *testing.T, srv *httptest.Server) *impersonatingClients {
+	t.Helper()
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(corev1.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+	return &impersonatingClients{
+		config:  &rest.Config{Host: srv.URL},
+		options: client.Options{Scheme: scheme.Scheme, Mapper: mapper},
+	}
+}
+
+func TestImpersonatingClient(t *testing.T) {
+	api := &apiServer{}
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+	users := newImpersonatingClients(t, srv)
+
+	sa := v1alpha1.NamespacedName{Namespace: "team-a", Name: "reader"}
+	kc, err := users.get(sa)
+	if err != nil {
+		t.Fatalf("get(...): %v", err)
+	}
+	if err := kc.Get(context.Background(), types.NamespacedName{Namespace: "team-a", Name: "parts"}, &corev1.ConfigMap{}); err != nil {
+		t.Fatalf("Get(...): %v", err)
+	}
+
+	if diff := cmp.Diff([]string{"system:serviceaccount:team-a:reader"}, api.users); diff != "" {
+		t.Errorf("Impersonate-User: -want, +got:\n%s", diff)
+	}
+	wantGroups := [][]string{{"system:serviceaccounts", "system:serviceaccounts:team-a", "system:authenticated"}}
+	if diff := cmp.Diff(wantGroups, api.groups); diff != "" {
+		t.Errorf("Impersonate-Group: -want, +got:\n%s", diff)
+	}
+
+	again, err := users.get(sa)
+	if err != nil {
+		t.Fatalf("get(...): %v", err)
+	}
+	if again != kc {
+		t.Errorf("get(...): want the cached client of %v", sa)
+	}
+	other, err := users.get(v1alpha1.NamespacedName{Namespace: "team-b", Name: "reader"})
+	if err != nil {
+		t.Fatalf("get(...): %v", err)
+	}
+	if other == kc {
+		t.Errorf("get(...): want a separate client per ServiceAccount")
+	}
+}
+
+func TestServiceAccountOf(t *testing.T) {
+	allowed := v1alpha1.NamespacedName{Namespace: "shared", Name: "reader"}
+	other := v1alpha1.NamespacedName{Namespace: "kube-system", Name: "admin"}
+	own := v1alpha1.NamespacedName{Namespace: "team-a", Name: "reader"}
+
+	pc := &apisv1alpha1.ProviderConfig{}
+	pc.SetName("default")
+	pc.Spec.AllowedServiceAccounts = []v1alpha1.NamespacedName{allowed}
+
+	config := &v1alpha1.Config{}
+	namespaced := &v1alpha1.NamespacedConfig{}
+	namespaced.SetNamespace("team-a")
+
+	cases := map[string]struct {
+		config  bool
+		sa      v1alpha1.NamespacedName
+		wantErr bool
+	}{
+		"ConfigAllowed":        {config: true, sa: allowed},
+		"ConfigNotAllowed":     {config: true, sa: other, wantErr: true},
+		"NamespacedOwnNS":      {sa: own},
+		"NamespacedNotAllowed": {sa: other, wantErr: true},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			sa := tc.sa
+			spec := &v1alpha1.ConfigSpec{}
+			spec.ForProvider.ServiceAccountRef = &sa
+			var err error
+			var got *v1alpha1.NamespacedName
+			if tc.config {
+				got, err = serviceAccountOf(config, spec, pc)
+			} else {
+				got, err = serviceAccountOf(namespaced, spec, pc)
+			}
+			if _, denied := isAccessDenied(err); denied != tc.wantErr {
+				t.Fatalf("serviceAccountOf(...): want denied %t, got %v", tc.wantErr, err)
+			}
+			if !tc.wantErr && (got == nil || *got != tc.sa) {
+				t.Errorf("serviceAccountOf(...): want %v, got %v", tc.sa, got)
+			}
+		})
+	}
+}
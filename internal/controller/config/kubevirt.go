@@ -0,0 +1,117 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+
+	v1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/config/v1alpha1"
+)
+
+// Keys KubeVirt reads from the Secret referenced by a cloud-init volume.
+const (
+	kubeVirtUserDataKey    = "userdata"
+	kubeVirtNetworkDataKey = "networkdata"
+
+	// kubeVirtVolumeName is the default name of the cloud-init volume
+	kubeVirtVolumeName = "cloudinitdisk"
+
+	errKubeVirtSizeFmt      = "KubeVirt data is %d bytes, exceeding the Secret size limit of %d bytes"
+	errGetVirtualMachineFmt = "cannot get VirtualMachine %q"
+	errNoVolumeFmt          = "VirtualMachine %q has no volume %q"
+	errUpdateVirtualMachine = "cannot update VirtualMachine"
+)
+
+var virtualMachineGVK = schema.GroupVersionKind{Group: "kubevirt.io", Version: "v1", Kind: "VirtualMachine"}
+
+// kubeVirtData returns the keys of the Secret KubeVirt reads the user-data
+// and network configuration of a seed from. The seed's meta-data is not
+// written; KubeVirt generates it.
+func (s renderSettings) kubeVirtData(files map[string]string) (map[string]string, error) {
+	if err := s.checkProfileOutput(); err != nil {
+		return nil, err
+	}
+	data := map[string]string{kubeVirtUserDataKey: files[s.seed.userData]}
+	if nd, ok := files[s.seed.networkData]; ok {
+		data[kubeVirtNetworkDataKey] = nd
+	}
+	size := 0
+	for _, v := range data {
+		size += len(v)
+	}
+	if size > corev1.MaxSecretSize {
+		return nil, errors.Errorf(errKubeVirtSizeFmt, size, corev1.MaxSecretSize)
+	}
+	return data, nil
+}
+
+// syncVirtualMachine checks that the cloud-init volume of the VirtualMachine
+// named by the KubeVirt profile references the output Secret, and patches it
+// to when update is true. It returns true when the volume is up to date, and
+// always when no VirtualMachine is named.
+func (e *ctrlClients) syncVirtualMachine(ctx context.Context, s renderSettings, data map[string]string, update bool) (bool, error) {
+	kv := s.output.kubeVirt
+	if s.output.profile != v1alpha1.OutputProfileKubeVirt || kv == nil || kv.VirtualMachineName == "" {
+		return true, nil
+	}
+	volume := kv.VolumeName
+	if volume == "" {
+		volume = kubeVirtVolumeName
+	}
+
+	vm := &unstructured.Unstructured{}
+	vm.SetGroupVersionKind(virtualMachineGVK)
+	if err := e.user.Get(ctx, types.NamespacedName{Namespace: s.output.namespace, Name: kv.VirtualMachineName}, vm); err != nil {
+		return false, errors.Wrapf(err, errGetVirtualMachineFmt, kv.VirtualMachineName)
+	}
+	volumes, _, err := unstructured.NestedSlice(vm.Object, "spec", "template", "spec", "volumes")
+	if err != nil {
+		return false, errors.Wrapf(err, errGetVirtualMachineFmt, kv.VirtualMachineName)
+	}
+
+	ref := map[string]interface{}{"name": s.output.name}
+	source := map[string]interface{}{"secretRef": ref}
+	if _, ok := data[kubeVirtNetworkDataKey]; ok {
+		source["networkDataSecretRef"] = ref
+	}
+	want := map[string]interface{}{"name": volume, "cloudInit" + s.seed.datasource: source}
+
+	for i, v := range volumes {
+		if m, ok := v.(map[string]interface{}); !ok || m["name"] != volume {
+			continue
+		}
+		if equality.Semantic.DeepEqual(v, want) {
+			return true, nil
+		}
+		if !update {
+			return false, nil
+		}
+		volumes[i] = want
+		if err := unstructured.SetNestedSlice(vm.Object, volumes, "spec", "template", "spec", "volumes"); err != nil {
+			return false, errors.Wrap(err, errUpdateVirtualMachine)
+		}
+		return true, errors.Wrap(e.user.Update(ctx, vm), errUpdateVirtualMachine)
+	}
+	return false, errors.Errorf(errNoVolumeFmt, kv.VirtualMachineName, volume)
+}
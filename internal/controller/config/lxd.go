@@ -0,0 +1,40 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+// Config keys LXD and Incus read cloud-init data from.
+const (
+	lxdUserDataKey      = "cloud-init.user-data"
+	lxdVendorDataKey    = "cloud-init.vendor-data"
+	lxdNetworkConfigKey = "cloud-init.network-config"
+)
+
+// lxdData returns the config keys of an LXD instance or profile holding a
+// NoCloud seed. LXD generates the meta-data itself.
+func (s renderSettings) lxdData(files map[string]string) (map[string]string, error) {
+	if err := s.checkProfileOutput(); err != nil {
+		return nil, err
+	}
+	data := map[string]string{lxdUserDataKey: files[noCloudUserDataKey]}
+	if vd, ok := files[noCloudVendorDataKey]; ok {
+		data[lxdVendorDataKey] = vd
+	}
+	if nc, ok := files[noCloudNetworkConfigKey]; ok {
+		data[lxdNetworkConfigKey] = nc
+	}
+	return data, nil
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	v1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/config/v1alpha1"
+)
+
+const (
+	errNamespaceRequiredFmt = "%s %q requires a namespace"
+	errNamespaceConfinedFmt = "%s %q must be in namespace %q, the namespace of the NamespacedConfig"
+)
+
+// configOf returns the spec a Config or NamespacedConfig is rendered from,
+// and the status its observation is written to. The spec of a
+// NamespacedConfig is a copy confined to its own namespace.
+func configOf(mg resource.Managed) (*v1alpha1.ConfigSpec, *v1alpha1.ConfigStatus, error) {
+	switch cr := mg.(type) {
+	case *v1alpha1.Config:
+		return &cr.Spec, &cr.Status, requireNamespaces(&cr.Spec)
+	case *v1alpha1.NamespacedConfig:
+		spec := cr.Spec.DeepCopy()
+		return spec, &cr.Status, confineNamespaces(spec, cr.GetNamespace())
+	}
+	return nil, nil, errors.New(errNotConfig)
+}
+
+// namespacedRef is an object reference of a ConfigSpec
+type namespacedRef struct {
+	kind string
+	ref  *v1alpha1.NamespacedName
+}
+
+// namespacedRefs returns the object references of spec. The references point
+// into spec, so they may be updated in place.
+func namespacedRefs(spec *v1alpha1.ConfigSpec) []namespacedRef {
+	var refs []namespacedRef
+	for i := range spec.ForProvider.Parts {
+		p := &spec.ForProvider.Parts[i]
+		if p.ConfigMapKeyRef != nil {
+			refs = append(refs, namespacedRef{kind: "ConfigMap", ref: &p.ConfigMapKeyRef.NamespacedName})
+		}
+		if p.SecretKeyRef != nil {
+			refs = append(refs, namespacedRef{kind: "Secret", ref: &p.SecretKeyRef.NamespacedName})
+		}
+		if p.OCIArtifactRef != nil && p.OCIArtifactRef.PullSecretRef != nil {
+			refs = append(refs, namespacedRef{kind: "Secret", ref: p.OCIArtifactRef.PullSecretRef})
+		}
+	}
+	if sa := spec.ForProvider.ServiceAccountRef; sa != nil {
+		refs = append(refs, namespacedRef{kind: "ServiceAccount", ref: sa})
+	}
+	if o := spec.WriteCloudInitToRef; o != nil {
+		kind := string(o.Kind)
+		if kind == "" {
+			kind = "output"
+		}
+		refs = append(refs, namespacedRef{kind: kind, ref: &o.NamespacedName})
+	}
+	return refs
+}
+
+// requireNamespaces returns an error when a reference of a cluster scoped
+// Config omits its namespace
+func requireNamespaces(spec *v1alpha1.ConfigSpec) error {
+	for _, r := range namespacedRefs(spec) {
+		if r.ref.Namespace == "" {
+			return errors.Errorf(errNamespaceRequiredFmt, r.kind, r.ref.Name)
+		}
+	}
+	return nil
+}
+
+// confineNamespaces defaults the references of a NamespacedConfig to its
+// namespace, and returns an error when one points to another namespace
+func confineNamespaces(spec *v1alpha1.ConfigSpec, namespace string) error {
+	for _, r := range namespacedRefs(spec) {
+		switch r.ref.Namespace {
+		case "":
+			r.ref.Namespace = namespace
+		case namespace:
+		default:
+			return errors.Errorf(errNamespaceConfinedFmt, r.kind, r.ref.Name, namespace)
+		}
+	}
+	return nil
+}
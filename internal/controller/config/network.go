@@ -0,0 +1,457 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/yaml"
+
+	v1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/config/v1alpha1"
+)
+
+const (
+	errNetworkConfig     = "cannot render network-config"
+	errVersion1MatchFmt  = "ethernet %q: network-config version 1 can only match interfaces by MAC address or exact name"
+	errVersion1SubnetFmt = "interface %q: network-config version 1 needs an address or DHCP to attach nameservers and routes to"
+	errRouteFmt          = "interface %q: invalid route destination %q"
+	errInterfaceCycleFmt = "interface %q is built on itself"
+)
+
+// netplan is a version 2 network-config
+type netplan struct {
+	Version   int                        `json:"version"`
+	Ethernets map[string]netplanEthernet `json:"ethernets,omitempty"`
+	Bonds     map[string]netplanBond     `json:"bonds,omitempty"`
+	VLANs     map[string]netplanVLAN     `json:"vlans,omitempty"`
+	Bridges   map[string]netplanBridge   `json:"bridges,omitempty"`
+}
+
+type netplanInterface struct {
+	DHCP4       *bool                 `json:"dhcp4,omitempty"`
+	DHCP6       *bool                 `json:"dhcp6,omitempty"`
+	Addresses   []string              `json:"addresses,omitempty"`
+	Gateway4    string                `json:"gateway4,omitempty"`
+	Gateway6    string                `json:"gateway6,omitempty"`
+	MTU         *int                  `json:"mtu,omitempty"`
+	Nameservers *v1alpha1.Nameservers `json:"nameservers,omitempty"`
+	Routes      []v1alpha1.Route      `json:"routes,omitempty"`
+}
+
+type netplanMatch struct {
+	Name       string `json:"name,omitempty"`
+	MACAddress string `json:"macaddress,omitempty"`
+	Driver     string `json:"driver,omitempty"`
+}
+
+type netplanEthernet struct {
+	netplanInterface
+	Match   *netplanMatch `json:"match,omitempty"`
+	SetName string        `json:"set-name,omitempty"`
+}
+
+type netplanBondParameters struct {
+	Mode               string `json:"mode,omitempty"`
+	Primary            string `json:"primary,omitempty"`
+	MIIMonitorInterval *int   `json:"mii-monitor-interval,omitempty"`
+	LACPRate           string `json:"lacp-rate,omitempty"`
+	TransmitHashPolicy string `json:"transmit-hash-policy,omitempty"`
+}
+
+type netplanBond struct {
+	netplanInterface
+	Interfaces []string               `json:"interfaces"`
+	Parameters *netplanBondParameters `json:"parameters,omitempty"`
+}
+
+type netplanVLAN struct {
+	netplanInterface
+	ID   int    `json:"id"`
+	Link string `json:"link"`
+}
+
+type netplanBridgeParameters struct {
+	STP          *bool `json:"stp,omitempty"`
+	ForwardDelay *int  `json:"forward-delay,omitempty"`
+}
+
+type netplanBridge struct {
+	netplanInterface
+	Interfaces []string                 `json:"interfaces,omitempty"`
+	Parameters *netplanBridgeParameters `json:"parameters,omitempty"`
+}
+
+// renderNetworkConfig returns nc as a network-config document of its
+// version. It is validated like the webhook does, since the webhook may not
+// be deployed.
+func renderNetworkConfig(nc *v1alpha1.NetworkConfig) (string, error) {
+	if errs := validateNetworkConfig(field.NewPath("spec", "forProvider", "networkConfig"), nc); len(errs) > 0 {
+		return "", errors.Wrap(errs.ToAggregate(), errNetworkConfig)
+	}
+	var doc interface{} = version2(nc)
+	if nc.Version == 1 {
+		v1, err := version1(nc)
+		if err != nil {
+			return "", errors.Wrap(err, errNetworkConfig)
+		}
+		doc = v1
+	}
+	b, err := yaml.Marshal(doc)
+	return string(b), errors.Wrap(err, errNetworkConfig)
+}
+
+// version2 returns nc as a version 2 network-config
+func version2(nc *v1alpha1.NetworkConfig) *netplan {
+	n := &netplan{Version: 2}
+	for id, e := range nc.Ethernets {
+		if n.Ethernets == nil {
+			n.Ethernets = map[string]netplanEthernet{}
+		}
+		ne := netplanEthernet{netplanInterface: netplanSettings(e.InterfaceSettings), SetName: e.SetName}
+		if m := e.Match; m != nil {
+			ne.Match = &netplanMatch{Name: m.Name, MACAddress: m.MACAddress, Driver: m.Driver}
+		}
+		n.Ethernets[id] = ne
+	}
+	for id, b := range nc.Bonds {
+		if n.Bonds == nil {
+			n.Bonds = map[string]netplanBond{}
+		}
+		nb := netplanBond{netplanInterface: netplanSettings(b.InterfaceSettings), Interfaces: b.Interfaces}
+		if p := b.Parameters; p != nil {
+			nb.Parameters = &netplanBondParameters{
+				Mode:               p.Mode,
+				Primary:            p.Primary,
+				MIIMonitorInterval: p.MIIMonitorInterval,
+				LACPRate:           p.LACPRate,
+				TransmitHashPolicy: p.TransmitHashPolicy,
+			}
+		}
+		n.Bonds[id] = nb
+	}
+	for id, v := range nc.VLANs {
+		if n.VLANs == nil {
+			n.VLANs = map[string]netplanVLAN{}
+		}
+		n.VLANs[id] = netplanVLAN{netplanInterface: netplanSettings(v.InterfaceSettings), ID: v.ID, Link: v.Link}
+	}
+	for id, b := range nc.Bridges {
+		if n.Bridges == nil {
+			n.Bridges = map[string]netplanBridge{}
+		}
+		nb := netplanBridge{netplanInterface: netplanSettings(b.InterfaceSettings), Interfaces: b.Interfaces}
+		if p := b.Parameters; p != nil {
+			nb.Parameters = &netplanBridgeParameters{STP: p.STP, ForwardDelay: p.ForwardDelay}
+		}
+		n.Bridges[id] = nb
+	}
+	return n
+}
+
+func netplanSettings(s v1alpha1.InterfaceSettings) netplanInterface {
+	return netplanInterface{
+		DHCP4:       s.DHCP4,
+		DHCP6:       s.DHCP6,
+		Addresses:   s.Addresses,
+		Gateway4:    s.Gateway4,
+		Gateway6:    s.Gateway6,
+		MTU:         s.MTU,
+		Nameservers: s.Nameservers,
+		Routes:      s.Routes,
+	}
+}
+
+// version1 translates nc to a version 1 network-config. Interfaces are
+// written after the interfaces they are built on, and refer to each other by
+// their version 1 names.
+func version1(nc *v1alpha1.NetworkConfig) (map[string]interface{}, error) {
+	order, err := interfaceOrder(nc)
+	if err != nil {
+		return nil, err
+	}
+	names := version1Names(nc)
+	name := func(id string) string {
+		if n, ok := names[id]; ok {
+			return n
+		}
+		return id
+	}
+	linked := func(ids []string) []string {
+		out := make([]string, 0, len(ids))
+		for _, id := range ids {
+			out = append(out, name(id))
+		}
+		return out
+	}
+
+	config := make([]map[string]interface{}, 0, len(order))
+	for _, id := range order {
+		var c map[string]interface{}
+		var settings v1alpha1.InterfaceSettings
+		if e, ok := nc.Ethernets[id]; ok {
+			if c, err = version1Ethernet(id, e); err != nil {
+				return nil, err
+			}
+			settings = e.InterfaceSettings
+		}
+		if b, ok := nc.Bonds[id]; ok {
+			c = map[string]interface{}{"type": "bond", "name": id, "bond_interfaces": linked(b.Interfaces)}
+			if p := b.Parameters; p != nil {
+				params := map[string]interface{}{}
+				setParam(params, "bond-mode", p.Mode)
+				setParam(params, "bond-primary", name(p.Primary))
+				setParam(params, "bond-lacp-rate", p.LACPRate)
+				setParam(params, "bond-xmit-hash-policy", p.TransmitHashPolicy)
+				if p.MIIMonitorInterval != nil {
+					params["bond-miimon"] = *p.MIIMonitorInterval
+				}
+				c["params"] = params
+			}
+			settings = b.InterfaceSettings
+		}
+		if b, ok := nc.Bridges[id]; ok {
+			c = map[string]interface{}{"type": "bridge", "name": id, "bridge_interfaces": linked(b.Interfaces)}
+			if p := b.Parameters; p != nil {
+				params := map[string]interface{}{}
+				if p.STP != nil {
+					params["bridge_stp"] = "off"
+					if *p.STP {
+						params["bridge_stp"] = "on"
+					}
+				}
+				if p.ForwardDelay != nil {
+					params["bridge_fd"] = *p.ForwardDelay
+				}
+				c["params"] = params
+			}
+			settings = b.InterfaceSettings
+		}
+		if v, ok := nc.VLANs[id]; ok {
+			c = map[string]interface{}{"type": "vlan", "name": id, "vlan_link": name(v.Link), "vlan_id": v.ID}
+			settings = v.InterfaceSettings
+		}
+		if err := version1Settings(c, id, settings); err != nil {
+			return nil, err
+		}
+		config = append(config, c)
+	}
+	return map[string]interface{}{"version": 1, "config": config}, nil
+}
+
+// version1Ethernet returns the version 1 config of the ethernet id
+func version1Ethernet(id string, e v1alpha1.Ethernet) (map[string]interface{}, error) {
+	c := map[string]interface{}{"type": "physical", "name": version1EthernetName(id, e)}
+	if m := e.Match; m != nil {
+		switch {
+		case m.Driver != "" || strings.ContainsAny(m.Name, "*?["):
+			return nil, errors.Errorf(errVersion1MatchFmt, id)
+		case m.MACAddress != "":
+			c["mac_address"] = m.MACAddress
+		}
+	}
+	return c, nil
+}
+
+// version1Names returns the version 1 names of ethernets by their ID.
+// Version 2 refers to ethernets by ID, while version 1 only knows the name
+// they are matched by or renamed to. Other interfaces are named by their ID.
+func version1Names(nc *v1alpha1.NetworkConfig) map[string]string {
+	names := map[string]string{}
+	for id, e := range nc.Ethernets {
+		names[id] = version1EthernetName(id, e)
+	}
+	return names
+}
+
+func version1EthernetName(id string, e v1alpha1.Ethernet) string {
+	switch {
+	case e.SetName != "":
+		return e.SetName
+	case e.Match != nil && e.Match.MACAddress == "" && e.Match.Name != "":
+		return e.Match.Name
+	}
+	return id
+}
+
+// interfaceOrder returns the IDs of the interfaces of nc, each after the
+// interfaces it is built on, e.g. a bridge after the VLAN it bridges.
+// Otherwise ethernets come first, then bonds, bridges and VLANs, by ID.
+func interfaceOrder(nc *v1alpha1.NetworkConfig) ([]string, error) {
+	deps := map[string][]string{}
+	var ids []string
+	for _, id := range sortedKeys(nc.Ethernets) {
+		deps[id] = nil
+		ids = append(ids, id)
+	}
+	for _, id := range sortedKeys(nc.Bonds) {
+		deps[id] = nc.Bonds[id].Interfaces
+		ids = append(ids, id)
+	}
+	for _, id := range sortedKeys(nc.Bridges) {
+		deps[id] = nc.Bridges[id].Interfaces
+		ids = append(ids, id)
+	}
+	for _, id := range sortedKeys(nc.VLANs) {
+		deps[id] = []string{nc.VLANs[id].Link}
+		ids = append(ids, id)
+	}
+
+	const visiting, visited = 1, 2
+	state := map[string]int{}
+	order := make([]string, 0, len(ids))
+	var visit func(id string) error
+	visit = func(id string) error {
+		switch state[id] {
+		case visiting:
+			return errors.Errorf(errInterfaceCycleFmt, id)
+		case visited:
+			return nil
+		}
+		state[id] = visiting
+		for _, d := range deps[id] {
+			if _, ok := deps[d]; !ok {
+				continue
+			}
+			if err := visit(d); err != nil {
+				return err
+			}
+		}
+		state[id] = visited
+		order = append(order, id)
+		return nil
+	}
+	for _, id := range ids {
+		if err := visit(id); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// version1Settings adds the subnets of the interface id to its version 1
+// config c. Gateways, nameservers and routes are attached to its first
+// subnet of their address family.
+func version1Settings(c map[string]interface{}, id string, s v1alpha1.InterfaceSettings) error {
+	if s.MTU != nil {
+		c["mtu"] = *s.MTU
+	}
+	subnets := make([]map[string]interface{}, 0)
+	if boolValue(s.DHCP4) {
+		subnets = append(subnets, map[string]interface{}{"type": "dhcp4"})
+	}
+	if boolValue(s.DHCP6) {
+		subnets = append(subnets, map[string]interface{}{"type": "dhcp6"})
+	}
+	for _, a := range s.Addresses {
+		sn := map[string]interface{}{"type": "static", "address": a}
+		if isIPv6(a) {
+			sn["type"] = "static6"
+		}
+		subnets = append(subnets, sn)
+	}
+
+	first := func(types ...string) map[string]interface{} {
+		for _, sn := range subnets {
+			for _, t := range types {
+				if sn["type"] == t {
+					return sn
+				}
+			}
+		}
+		return nil
+	}
+	// gateways are validated to have a static address of their family
+	if sn := first("static"); sn != nil && s.Gateway4 != "" {
+		sn["gateway"] = s.Gateway4
+	}
+	if sn := first("static6"); sn != nil && s.Gateway6 != "" {
+		sn["gateway"] = s.Gateway6
+	}
+	if ns := s.Nameservers; ns != nil {
+		if len(subnets) == 0 {
+			return errors.Errorf(errVersion1SubnetFmt, id)
+		}
+		setParam(subnets[0], "dns_nameservers", ns.Addresses)
+		setParam(subnets[0], "dns_search", ns.Search)
+	}
+	for _, r := range s.Routes {
+		to := r.To
+		if to == "default" {
+			to = "0.0.0.0/0"
+			if isIPv6(r.Via) {
+				to = "::/0"
+			}
+		}
+		ip, cidr, err := net.ParseCIDR(to)
+		if err != nil {
+			return errors.Errorf(errRouteFmt, id, r.To)
+		}
+		sn := first("dhcp4", "static")
+		if ip.To4() == nil {
+			sn = first("dhcp6", "static6")
+		}
+		if sn == nil {
+			return errors.Errorf(errVersion1SubnetFmt, id)
+		}
+		prefix, _ := cidr.Mask.Size()
+		route := map[string]interface{}{"network": cidr.IP.String(), "prefix": prefix, "gateway": r.Via}
+		if r.Metric != nil {
+			route["metric"] = *r.Metric
+		}
+		routes, _ := sn["routes"].([]map[string]interface{})
+		sn["routes"] = append(routes, route)
+	}
+	if len(subnets) > 0 {
+		c["subnets"] = subnets
+	}
+	return nil
+}
+
+// setParam sets key to v unless v is empty
+func setParam(m map[string]interface{}, key string, v interface{}) {
+	switch t := v.(type) {
+	case string:
+		if t == "" {
+			return
+		}
+	case []string:
+		if len(t) == 0 {
+			return
+		}
+	}
+	m[key] = v
+}
+
+// isIPv6 returns true when s is an IPv6 address, with or without a prefix
+func isIPv6(s string) bool {
+	return strings.Contains(s, ":")
+}
+
+// sortedKeys returns the keys of the map m in order
+func sortedKeys(m interface{}) []string {
+	keys := make([]string, 0)
+	for _, k := range reflect.ValueOf(m).MapKeys() {
+		keys = append(keys, k.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,378 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/yaml"
+
+	v1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/config/v1alpha1"
+)
+
+// networkConfig returns the networkConfig of a Config spec written as YAML
+func networkConfig(t *testing.T, s string) *v1alpha1.NetworkConfig {
+	t.Helper()
+	nc := &v1alpha1.NetworkConfig{}
+	if err := yaml.UnmarshalStrict([]byte(s), nc); err != nil {
+		t.Fatal(err)
+	}
+	return nc
+}
+
+func TestRenderNetworkConfigVersion1(t *testing.T) {
+	cases := map[string]struct {
+		nc   string
+		want string
+	}{
+		"Ethernets": {
+			nc: `
+version: 1
+ethernets:
+  lan:
+    match: {macAddress: "52:54:00:12:34:56"}
+    setName: lan0
+    dhcp4: true
+    mtu: 9000
+  ens3:
+    match: {name: ens3}
+    addresses: [192.0.2.10/24, 2001:db8::10/64]
+    gateway4: 192.0.2.1
+    gateway6: 2001:db8::1
+    nameservers:
+      addresses: [192.0.2.53]
+      search: [example.com]
+  eth2:
+    dhcp6: true
+`,
+			want: `
+version: 1
+config:
+- type: physical
+  name: ens3
+  subnets:
+  - type: static
+    address: 192.0.2.10/24
+    gateway: 192.0.2.1
+    dns_nameservers: [192.0.2.53]
+    dns_search: [example.com]
+  - type: static6
+    address: 2001:db8::10/64
+    gateway: 2001:db8::1
+- type: physical
+  name: eth2
+  subnets:
+  - type: dhcp6
+- type: physical
+  name: lan0
+  mac_address: "52:54:00:12:34:56"
+  mtu: 9000
+  subnets:
+  - type: dhcp4
+`,
+		},
+		"Routes": {
+			nc: `
+version: 1
+ethernets:
+  eth0:
+    dhcp4: true
+    addresses: [2001:db8::10/64]
+    routes:
+    - {to: default, via: 192.0.2.1, metric: 100}
+    - {to: 10.0.0.0/8, via: 192.0.2.254}
+    - {to: default, via: "2001:db8::1"}
+`,
+			want: `
+version: 1
+config:
+- type: physical
+  name: eth0
+  subnets:
+  - type: dhcp4
+    routes:
+    - {network: 0.0.0.0, prefix: 0, gateway: 192.0.2.1, metric: 100}
+    - {network: 10.0.0.0, prefix: 8, gateway: 192.0.2.254}
+  - type: static6
+    address: 2001:db8::10/64
+    routes:
+    - {network: "::", prefix: 0, gateway: "2001:db8::1"}
+`,
+		},
+		"Bond": {
+			nc: `
+version: 1
+ethernets:
+  e1:
+    match: {macAddress: "52:54:00:00:00:01"}
+    setName: nic1
+  e2:
+    match: {macAddress: "52:54:00:00:00:02"}
+    setName: nic2
+bonds:
+  bond0:
+    interfaces: [e1, e2]
+    dhcp4: true
+    parameters:
+      mode: active-backup
+      primary: e1
+      miiMonitorInterval: 100
+      lacpRate: fast
+      transmitHashPolicy: layer3+4
+`,
+			want: `
+version: 1
+config:
+- type: physical
+  name: nic1
+  mac_address: "52:54:00:00:00:01"
+- type: physical
+  name: nic2
+  mac_address: "52:54:00:00:00:02"
+- type: bond
+  name: bond0
+  bond_interfaces: [nic1, nic2]
+  params:
+    bond-mode: active-backup
+    bond-primary: nic1
+    bond-miimon: 100
+    bond-lacp-rate: fast
+    bond-xmit-hash-policy: layer3+4
+  subnets:
+  - type: dhcp4
+`,
+		},
+		"BridgeOverVLAN": {
+			nc: `
+version: 1
+ethernets:
+  uplink:
+    match: {name: enp1s0}
+vlans:
+  vlan100:
+    id: 100
+    link: uplink
+bridges:
+  br0:
+    interfaces: [vlan100]
+    addresses: [192.0.2.10/24]
+    gateway4: 192.0.2.1
+    parameters: {stp: false, forwardDelay: 0}
+`,
+			want: `
+version: 1
+config:
+- type: physical
+  name: enp1s0
+- type: vlan
+  name: vlan100
+  vlan_link: enp1s0
+  vlan_id: 100
+- type: bridge
+  name: br0
+  bridge_interfaces: [vlan100]
+  params: {bridge_stp: "off", bridge_fd: 0}
+  subnets:
+  - type: static
+    address: 192.0.2.10/24
+    gateway: 192.0.2.1
+`,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			doc, err := renderNetworkConfig(networkConfig(t, tc.nc))
+			if err != nil {
+				t.Fatalf("renderNetworkConfig(...): %v", err)
+			}
+			var got, want interface{}
+			if err := yaml.Unmarshal([]byte(doc), &got); err != nil {
+				t.Fatal(err)
+			}
+			if err := yaml.Unmarshal([]byte(tc.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("renderNetworkConfig(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateNetworkConfig(t *testing.T) {
+	np := field.NewPath("spec", "forProvider", "networkConfig")
+	eth0 := np.Child("ethernets").Key("eth0")
+	cases := map[string]struct {
+		nc   string
+		want field.ErrorList
+	}{
+		"Valid": {
+			nc: `
+ethernets:
+  eth0: {dhcp4: true}
+bonds:
+  bond0: {interfaces: [eth0]}
+`,
+			want: field.ErrorList{},
+		},
+		"DuplicateID": {
+			nc: `
+ethernets:
+  eth0: {}
+bonds:
+  eth0: {interfaces: []}
+`,
+			want: field.ErrorList{field.Duplicate(np.Child("bonds"), "eth0")},
+		},
+		"BondInterfaceNotFound": {
+			nc: `
+ethernets:
+  eth0: {}
+bonds:
+  bond0: {interfaces: [eth0, eth1]}
+`,
+			want: field.ErrorList{field.NotFound(np.Child("bonds").Key("bond0").Child("interfaces").Index(1), "eth1")},
+		},
+		"BridgeInterfaceNotFound": {
+			nc: `
+bridges:
+  br0: {interfaces: [eth0]}
+`,
+			want: field.ErrorList{field.NotFound(np.Child("bridges").Key("br0").Child("interfaces").Index(0), "eth0")},
+		},
+		"VLANLinkNotFound": {
+			nc: `
+vlans:
+  vlan100: {id: 100, link: eth0}
+`,
+			want: field.ErrorList{field.NotFound(np.Child("vlans").Key("vlan100").Child("link"), "eth0")},
+		},
+		"SetNameWithoutMatch": {
+			nc: `
+ethernets:
+  eth0: {setName: lan0}
+`,
+			want: field.ErrorList{field.Required(eth0.Child("match"), "setName requires match")},
+		},
+		"MACAddress": {
+			nc: `
+ethernets:
+  eth0: {match: {macAddress: "52:54:00"}}
+`,
+			want: field.ErrorList{field.Invalid(eth0.Child("match", "macAddress"), "52:54:00", "address 52:54:00: invalid MAC address")},
+		},
+		"Address": {
+			nc: `
+ethernets:
+  eth0: {addresses: [192.0.2.10]}
+`,
+			want: field.ErrorList{field.Invalid(eth0.Child("addresses").Index(0), "192.0.2.10", "must be an address in CIDR notation")},
+		},
+		"GatewayFamily": {
+			nc: `
+ethernets:
+  eth0: {addresses: [192.0.2.10/24], gateway4: "2001:db8::1"}
+`,
+			want: field.ErrorList{field.Invalid(eth0.Child("gateway4"), "2001:db8::1", "must be an IP address of its family")},
+		},
+		"GatewayWithoutAddress": {
+			nc: `
+ethernets:
+  eth0: {dhcp6: true, gateway6: "2001:db8::1"}
+`,
+			want: field.ErrorList{field.Forbidden(eth0.Child("gateway6"), "requires a static address of its family")},
+		},
+		"Nameserver": {
+			nc: `
+ethernets:
+  eth0: {dhcp4: true, nameservers: {addresses: [dns.example.com]}}
+`,
+			want: field.ErrorList{field.Invalid(eth0.Child("nameservers", "addresses").Index(0), "dns.example.com", "must be an IP address")},
+		},
+		"Route": {
+			nc: `
+ethernets:
+  eth0:
+    dhcp4: true
+    routes: [{to: 10.0.0.0, via: gateway}]
+`,
+			want: field.ErrorList{
+				field.Invalid(eth0.Child("routes").Index(0).Child("to"), "10.0.0.0", "must be a destination in CIDR notation, or default"),
+				field.Invalid(eth0.Child("routes").Index(0).Child("via"), "gateway", "must be an IP address"),
+			},
+		},
+		"Cycle": {
+			nc: `
+bonds:
+  bond0: {interfaces: [br0]}
+bridges:
+  br0: {interfaces: [bond0]}
+`,
+			want: field.ErrorList{field.Forbidden(np, `interface "bond0" is built on itself`)},
+		},
+		"Version1WildcardMatch": {
+			nc: `
+version: 1
+ethernets:
+  eth0: {match: {name: "en*"}}
+`,
+			want: field.ErrorList{field.Invalid(np.Child("version"), 1, `ethernet "eth0": network-config version 1 can only match interfaces by MAC address or exact name`)},
+		},
+		"Version1DriverMatch": {
+			nc: `
+version: 1
+ethernets:
+  eth0: {match: {driver: virtio_net}}
+`,
+			want: field.ErrorList{field.Invalid(np.Child("version"), 1, `ethernet "eth0": network-config version 1 can only match interfaces by MAC address or exact name`)},
+		},
+		"Version1NameserversWithoutSubnet": {
+			nc: `
+version: 1
+ethernets:
+  eth0: {nameservers: {addresses: [192.0.2.53]}}
+`,
+			want: field.ErrorList{field.Invalid(np.Child("version"), 1, `interface "eth0": network-config version 1 needs an address or DHCP to attach nameservers and routes to`)},
+		},
+		"Version1RouteWithoutSubnet": {
+			nc: `
+version: 1
+ethernets:
+  eth0:
+    dhcp4: true
+    routes: [{to: "2001:db8:1::/48", via: "2001:db8::1"}]
+`,
+			want: field.ErrorList{field.Invalid(np.Child("version"), 1, `interface "eth0": network-config version 1 needs an address or DHCP to attach nameservers and routes to`)},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := validateNetworkConfig(np, networkConfig(t, tc.nc))
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("validateNetworkConfig(...): -want, +got:\n%s", diff)
+			}
+			if len(tc.want) == 0 {
+				return
+			}
+			if _, err := renderNetworkConfig(networkConfig(t, tc.nc)); err == nil {
+				t.Errorf("renderNetworkConfig(...): want error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	v1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/config/v1alpha1"
+)
+
+// Keys of a NoCloud seed, named as the files cloud-init reads from a seed
+// directory or cidata volume.
+const (
+	noCloudUserDataKey      = "user-data"
+	noCloudMetaDataKey      = "meta-data"
+	noCloudNetworkConfigKey = "network-config"
+	noCloudVendorDataKey    = "vendor-data"
+
+	// noCloudVolumeID labels NoCloud images, as cloud-init expects
+	noCloudVolumeID = "cidata"
+
+	errMetaData = "cannot render NoCloud meta-data"
+)
+
+// noCloudSeed returns a NoCloud seed, whose files are at its root. A typed
+// network configuration takes the place of that of the seed.
+func noCloudSeed(nc *v1alpha1.NoCloudSeed, network *v1alpha1.NetworkConfig) *seed {
+	return &seed{
+		datasource:  "NoCloud",
+		volumeID:    noCloudVolumeID,
+		userData:    noCloudUserDataKey,
+		networkData: noCloudNetworkConfigKey,
+		files: func(userData, partsDigest string) (map[string]string, error) {
+			if network == nil {
+				return noCloudFiles(nc, userData, partsDigest)
+			}
+			n, err := renderNetworkConfig(network)
+			if err != nil {
+				return nil, err
+			}
+			withNetwork := *nc
+			withNetwork.NetworkConfig = n
+			return noCloudFiles(&withNetwork, userData, partsDigest)
+		},
+	}
+}
+
+// noCloudFiles returns the files of a NoCloud seed holding the user-data
+func noCloudFiles(seed *v1alpha1.NoCloudSeed, userData, partsDigest string) (map[string]string, error) {
+	data := map[string]string{noCloudUserDataKey: userData}
+	if seed.NetworkConfig != "" {
+		data[noCloudNetworkConfigKey] = seed.NetworkConfig
+	}
+	if seed.VendorData != "" {
+		data[noCloudVendorDataKey] = seed.VendorData
+	}
+
+	md := map[string]string{"instance-id": seed.MetaData.InstanceID}
+	if md["instance-id"] == "" {
+		md["instance-id"] = instanceID(seed.MetaData.LocalHostname, partsDigest, data)
+	}
+	if seed.MetaData.LocalHostname != "" {
+		md["local-hostname"] = seed.MetaData.LocalHostname
+	}
+	b, err := yaml.Marshal(md)
+	if err != nil {
+		return nil, errors.Wrap(err, errMetaData)
+	}
+	data[noCloudMetaDataKey] = string(b)
+	return data, nil
+}
+
+// instanceID derives an instance-id from the content of a NoCloud seed, so
+// that cloud-init applies a changed seed to an existing instance. The parts
+// are hashed instead of the user-data, whose boundary may change on every
+// render.
+func instanceID(hostname, partsDigest string, data map[string]string) string {
+	h := sha256.New()
+	for _, v := range []string{hostname, partsDigest, data[noCloudNetworkConfigKey], data[noCloudVendorDataKey]} {
+		_, _ = h.Write([]byte(v))
+		_, _ = h.Write([]byte{0})
+	}
+	return "iid-" + hex.EncodeToString(h.Sum(nil))[:16]
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"unicode/utf8"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	v1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/config/v1alpha1"
+)
+
+// outputObject returns an empty object of the kind, name and namespace of
+// the output target
+func outputObject(t outputTarget) client.Object {
+	om := metav1.ObjectMeta{Name: t.name, Namespace: t.namespace}
+	if t.kind == v1alpha1.OutputKindSecret {
+		return &corev1.Secret{ObjectMeta: om}
+	}
+	return &corev1.ConfigMap{ObjectMeta: om}
+}
+
+// generateOutput returns the output object holding the rendered data, owned
+// by the Config with the supplied UID
+func generateOutput(t outputTarget, owner types.UID, want map[string]string) client.Object {
+	o := outputObject(t)
+	o.SetLabels(mergeLabels(t.labels, map[string]string{v1alpha1.LabelKeyOwnerUID: string(owner)}))
+	switch obj := o.(type) {
+	case *corev1.Secret:
+		obj.Type = outputSecretType(t)
+		obj.Data = make(map[string][]byte, len(want))
+		for k, v := range want {
+			obj.Data[k] = []byte(v)
+		}
+	case *corev1.ConfigMap:
+		// ConfigMap data must be UTF-8, images are binary data
+		for k, v := range want {
+			if utf8.ValidString(v) {
+				if obj.Data == nil {
+					obj.Data = map[string]string{}
+				}
+				obj.Data[k] = v
+				continue
+			}
+			if obj.BinaryData == nil {
+				obj.BinaryData = map[string][]byte{}
+			}
+			obj.BinaryData[k] = []byte(v)
+		}
+	}
+	return o
+}
+
+// outputSecretType returns the type of Secrets written to the output target
+func outputSecretType(t outputTarget) corev1.SecretType {
+	if t.secretType != "" {
+		return t.secretType
+	}
+	return corev1.SecretTypeOpaque
+}
+
+// ownsOutput is true when the output object o was written by mg
+func ownsOutput(mg metav1.Object, o metav1.Object) bool {
+	return o.GetLabels()[v1alpha1.LabelKeyOwnerUID] == string(mg.GetUID())
+}
+
+// adoptsOutput is true when o is an output mg wrote before outputs were
+// labelled with their owner: o has no owner label, and mg has already
+// written its output, or is deleting it. The label is added by the next
+// update.
+func adoptsOutput(mg resource.Managed, o metav1.Object) bool {
+	if _, ok := o.GetLabels()[v1alpha1.LabelKeyOwnerUID]; ok {
+		return false
+	}
+	switch mg.GetCondition(xpv1.TypeReady).Reason {
+	case xpv1.ReasonAvailable, xpv1.ReasonDeleting:
+		return true
+	}
+	return false
+}
+
+// outputValues returns the data written at every key of an output object
+func outputValues(o client.Object) map[string]string {
+	data := map[string]string{}
+	switch obj := o.(type) {
+	case *corev1.Secret:
+		for k, v := range obj.Data {
+			data[k] = string(v)
+		}
+	case *corev1.ConfigMap:
+		for k, v := range obj.Data {
+			data[k] = v
+		}
+		for k, v := range obj.BinaryData {
+			data[k] = string(v)
+		}
+	}
+	return data
+}
+
+// outputValue returns the data written at a key of an output object
+func outputValue(o client.Object, key string) string {
+	switch obj := o.(type) {
+	case *corev1.Secret:
+		return string(obj.Data[key])
+	case *corev1.ConfigMap:
+		if v, ok := obj.BinaryData[key]; ok {
+			return string(v)
+		}
+		return obj.Data[key]
+	}
+	return ""
+}
+
+// outputUpToDate is true when the observed object holds the wanted data,
+// labels and Secret type
+func outputUpToDate(t outputTarget, o client.Object, want map[string]string) bool {
+	if s, ok := o.(*corev1.Secret); ok && s.Type != outputSecretType(t) {
+		return false
+	}
+	for k, v := range want {
+		if outputValue(o, k) != v {
+			return false
+		}
+	}
+	labels := o.GetLabels()
+	for k, v := range t.labels {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
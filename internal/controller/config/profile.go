@@ -0,0 +1,62 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"github.com/pkg/errors"
+
+	v1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/config/v1alpha1"
+)
+
+const (
+	errProfileEncodingFmt = "the %s profile writes plain user-data; gzip and base64Encode must be disabled"
+	errProfileKindFmt     = "the %s profile writes Secrets"
+	errProfileGzipFmt     = "the %s profile writes text; gzip requires base64Encode"
+)
+
+// profileUserDataKeys are the keys profiles write the user-data to
+var profileUserDataKeys = map[v1alpha1.OutputProfile]string{
+	v1alpha1.OutputProfileKubeVirt:        kubeVirtUserDataKey,
+	v1alpha1.OutputProfileClusterAPI:      clusterAPIValueKey,
+	v1alpha1.OutputProfileVMwareGuestInfo: guestInfoUserDataKey,
+	v1alpha1.OutputProfileLXD:             lxdUserDataKey,
+}
+
+// checkProfileOutput returns an error when the output cannot be read by the
+// consumer its profile writes for. KubeVirt and Cluster API read plain
+// user-data from Secrets, LXD reads plain user-data, and guestinfo values
+// cannot hold raw gzip data.
+func (s renderSettings) checkProfileOutput() error {
+	switch s.output.profile {
+	case v1alpha1.OutputProfileVMwareGuestInfo:
+		if s.gzip && !s.base64Encode {
+			return errors.Errorf(errProfileGzipFmt, s.output.profile)
+		}
+	case v1alpha1.OutputProfileLXD:
+		if s.gzip || s.base64Encode {
+			return errors.Errorf(errProfileEncodingFmt, s.output.profile)
+		}
+	case v1alpha1.OutputProfileKubeVirt, v1alpha1.OutputProfileClusterAPI:
+		if s.gzip || s.base64Encode {
+			return errors.Errorf(errProfileEncodingFmt, s.output.profile)
+		}
+		if s.output.kind != v1alpha1.OutputKindSecret {
+			return errors.Errorf(errProfileKindFmt, s.output.profile)
+		}
+	}
+	return nil
+}
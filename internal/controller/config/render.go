@@ -0,0 +1,81 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	v1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/config/v1alpha1"
+	apisv1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/v1alpha1"
+	clients "github.com/crossplane-contrib/provider-cloudinit/internal/clients"
+	"github.com/crossplane-contrib/provider-cloudinit/internal/clients/oci"
+)
+
+// An Output is rendered cloud-init data and the object it is written to
+type Output struct {
+	Kind      v1alpha1.OutputKind
+	Namespace string
+	Name      string
+	Key       string
+	Format    v1alpha1.OutputFormat
+	Labels    map[string]string
+
+	// UserData is the rendered cloud-init data, exactly as it is written
+	UserData string
+
+	// Data are all keys written to the object, including the user-data
+	Data map[string]string
+}
+
+// Render renders a Config or NamespacedConfig like its controller does,
+// reading sources with the supplied client. Outputs are not written.
+func Render(ctx context.Context, kube client.Client, mg resource.Managed, pc *apisv1alpha1.ProviderConfig) (Output, error) {
+	spec, _, err := configOf(mg)
+	if err != nil {
+		return Output{}, err
+	}
+	s, err := newRenderSettings(spec, pc)
+	if err != nil {
+		return Output{}, err
+	}
+	e := &ctrlClients{kube: kube, user: kube, oci: oci.NewClient(nil), pc: pc}
+	if err := e.authorizeOutput(ctx, s.output); err != nil {
+		return Output{}, err
+	}
+	r, err := e.renderCloudInit(ctx, spec, s)
+	if err != nil {
+		return Output{}, err
+	}
+	t := s.output
+	return Output{Kind: t.kind, Namespace: t.namespace, Name: t.name, Key: t.key, Format: t.format, Labels: t.labels, UserData: r.userData, Data: r.data}, nil
+}
+
+// Live returns the data currently written to the object of the Output by
+// key, or nil when the object does not exist.
+func Live(ctx context.Context, kube client.Client, out Output) (map[string]string, error) {
+	o := outputObject(outputTarget{kind: out.Kind, namespace: out.Namespace, name: out.Name})
+	if err := kube.Get(ctx, types.NamespacedName{Name: o.GetName(), Namespace: o.GetNamespace()}, o); err != nil {
+		return nil, errors.Wrap(resource.Ignore(clients.IsErrorNotFound, err), errGetOutput)
+	}
+	return outputValues(o), nil
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"archive/tar"
+	"bytes"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	v1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/config/v1alpha1"
+	"github.com/crossplane-contrib/provider-cloudinit/internal/iso9660"
+)
+
+const (
+	errImage   = "cannot write seed image"
+	errTarball = "cannot write seed archive"
+)
+
+// A seed is the layout of the files cloud-init reads from a datasource
+// volume, one of which holds the rendered user-data.
+type seed struct {
+	// datasource is the name of the cloud-init datasource reading the seed
+	datasource string
+
+	// volumeID labels images of the seed
+	volumeID string
+
+	// userData and networkData are the paths of the user-data and network
+	// configuration within the seed
+	userData    string
+	networkData string
+
+	// files returns the files of the seed by path. partsDigest identifies
+	// the parts rendered into the user-data.
+	files func(userData, partsDigest string) (map[string]string, error)
+}
+
+// seedOf returns the seed the parameters of a Config ask for, or nil
+func seedOf(p v1alpha1.ConfigParameters) *seed {
+	switch {
+	case p.ConfigDrive != nil:
+		return configDriveSeed(p.ConfigDrive)
+	case p.NoCloud != nil:
+		return noCloudSeed(p.NoCloud, p.NetworkConfig)
+	}
+	return nil
+}
+
+// defaultKey returns the output key of the seed written in format f
+func (sd *seed) defaultKey(f v1alpha1.OutputFormat) string {
+	switch f {
+	case v1alpha1.OutputFormatISO9660:
+		return sd.volumeID + ".iso"
+	case v1alpha1.OutputFormatTar:
+		return sd.volumeID + ".tar"
+	}
+	return path.Base(sd.userData)
+}
+
+// outputData returns the keys written to the output for the rendered
+// user-data. They are the user-data alone, the files of a seed keyed by their
+// base name, an image or archive of the seed, or the keys of a profile.
+func (s renderSettings) outputData(userData, partsDigest string) (map[string]string, error) {
+	if s.output.profile == v1alpha1.OutputProfileClusterAPI {
+		return s.clusterAPIData(userData)
+	}
+	if s.seed == nil {
+		return map[string]string{s.output.key: userData}, nil
+	}
+	files, err := s.seed.files(userData, partsDigest)
+	if err != nil {
+		return nil, err
+	}
+	switch s.output.profile {
+	case v1alpha1.OutputProfileKubeVirt:
+		return s.kubeVirtData(files)
+	case v1alpha1.OutputProfileVMwareGuestInfo:
+		return s.guestInfoData(files)
+	case v1alpha1.OutputProfileLXD:
+		return s.lxdData(files)
+	}
+
+	b := &bytes.Buffer{}
+	switch s.output.format {
+	case v1alpha1.OutputFormatISO9660:
+		img := make(map[string][]byte, len(files))
+		for k, v := range files {
+			img[k] = []byte(v)
+		}
+		if err := iso9660.Write(b, s.seed.volumeID, img); err != nil {
+			return nil, errors.Wrap(err, errImage)
+		}
+	case v1alpha1.OutputFormatTar:
+		if err := tarball(b, files); err != nil {
+			return nil, errors.Wrap(err, errTarball)
+		}
+	default:
+		data := make(map[string]string, len(files))
+		for k, v := range files {
+			data[path.Base(k)] = v
+		}
+		return data, nil
+	}
+	return map[string]string{s.output.key: b.String()}, nil
+}
+
+// tarball writes files to a tar archive, preceded by their parent
+// directories. Entries are sorted and carry no times or owners, so that the
+// same files always produce the same archive.
+func tarball(b *bytes.Buffer, files map[string]string) error {
+	names := make([]string, 0, len(files))
+	dirs := map[string]bool{}
+	for name := range files {
+		names = append(names, name)
+		for d := path.Dir(name); d != "."; d = path.Dir(d) {
+			if !dirs[d] {
+				dirs[d] = true
+				names = append(names, d+"/")
+			}
+		}
+	}
+	sort.Strings(names)
+
+	tw := tar.NewWriter(b)
+	for _, name := range names {
+		h := &tar.Header{Name: name, ModTime: time.Unix(0, 0), Format: tar.FormatUSTAR}
+		if strings.HasSuffix(name, "/") {
+			h.Typeflag, h.Mode = tar.TypeDir, 0755
+		} else {
+			h.Typeflag, h.Mode, h.Size = tar.TypeReg, 0644, int64(len(files[name]))
+		}
+		if err := tw.WriteHeader(h); err != nil {
+			return err
+		}
+		if _, err := tw.Write([]byte(files[name])); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
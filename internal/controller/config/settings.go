@@ -0,0 +1,207 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+
+	v1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/config/v1alpha1"
+	apisv1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/v1alpha1"
+	"github.com/crossplane-contrib/provider-cloudinit/internal/cloudinit"
+)
+
+// outputTarget identifies the object and key rendered data is written to
+type outputTarget struct {
+	kind      v1alpha1.OutputKind
+	format    v1alpha1.OutputFormat
+	name      string
+	namespace string
+	key       string
+	labels    map[string]string
+
+	// profile, when set, decides the keys written and the secretType
+	profile    v1alpha1.OutputProfile
+	kubeVirt   *v1alpha1.KubeVirtOutput
+	secretType corev1.SecretType
+}
+
+// renderSettings are the effective settings of a Config after the defaults
+// of its ProviderConfig have been merged in
+type renderSettings struct {
+	gzip             bool
+	base64Encode     bool
+	boundary         string
+	boundaryStrategy apisv1alpha1.BoundaryStrategy
+	sizeLimit        int64
+	output           outputTarget
+	seed             *seed
+	prepend          []v1alpha1.PartSpec
+	append           []v1alpha1.PartSpec
+}
+
+// newRenderSettings merges the ProviderConfig defaults into the settings of
+// a Config spec. Values set in the spec take precedence.
+func newRenderSettings(spec *v1alpha1.ConfigSpec, pc *apisv1alpha1.ProviderConfig) (renderSettings, error) {
+	s := renderSettings{
+		boundary:         spec.ForProvider.Boundary,
+		boundaryStrategy: apisv1alpha1.BoundaryStrategyRandom,
+		output:           outputTarget{kind: v1alpha1.OutputKindConfigMap, format: v1alpha1.OutputFormatKeys, key: configMapKey},
+	}
+
+	if pc != nil {
+		d := pc.Spec.Defaults
+		s.gzip = boolValue(d.Gzip)
+		s.base64Encode = boolValue(d.Base64Encode)
+		if d.BoundaryStrategy != "" {
+			s.boundaryStrategy = d.BoundaryStrategy
+		}
+		if s.boundary == "" && s.boundaryStrategy == apisv1alpha1.BoundaryStrategyStatic {
+			s.boundary = d.Boundary
+		}
+		if d.SizeLimit != nil {
+			s.sizeLimit = *d.SizeLimit
+		}
+		if d.OutputKind != "" {
+			s.output.kind = d.OutputKind
+		}
+		s.output.labels = mergeLabels(s.output.labels, d.Labels)
+		s.prepend = pc.Spec.BaselineParts.Prepend
+		s.append = pc.Spec.BaselineParts.Append
+	}
+
+	// profiles encode user-data as their consumer expects, whatever the
+	// ProviderConfig defaults
+	if ref := spec.WriteCloudInitToRef; ref != nil {
+		switch ref.Profile {
+		case v1alpha1.OutputProfileVMwareGuestInfo:
+			s.gzip, s.base64Encode = true, true
+		case v1alpha1.OutputProfileLXD:
+			s.gzip, s.base64Encode = false, false
+		case v1alpha1.OutputProfileKubeVirt, v1alpha1.OutputProfileClusterAPI:
+			s.gzip, s.base64Encode = false, false
+			s.output.kind = v1alpha1.OutputKindSecret
+		}
+	}
+
+	if spec.ForProvider.Gzip != nil {
+		s.gzip = *spec.ForProvider.Gzip
+	}
+	if spec.ForProvider.Base64Encode != nil {
+		s.base64Encode = *spec.ForProvider.Base64Encode
+	}
+
+	ref := spec.WriteCloudInitToRef
+	if ref == nil {
+		return s, errors.New(errNoOutputRef)
+	}
+	s.output.name = ref.Name
+	s.output.namespace = ref.Namespace
+	if ref.Key != "" {
+		s.output.key = ref.Key
+	}
+	if ref.Kind != "" {
+		s.output.kind = ref.Kind
+	}
+	if ref.Format != "" {
+		s.output.format = ref.Format
+	}
+	s.output.labels = mergeLabels(s.output.labels, ref.Labels)
+	s.output.profile = ref.Profile
+	s.output.kubeVirt = ref.KubeVirt
+
+	switch sd := seedOf(spec.ForProvider); {
+	case s.output.profile == v1alpha1.OutputProfileClusterAPI:
+		// bootstrap data is the user-data alone
+		s.output.key = profileUserDataKeys[s.output.profile]
+		s.output.secretType = clusterAPISecretType
+		if c := ref.ClusterAPI; c != nil {
+			s.output.labels = mergeLabels(s.output.labels, map[string]string{clusterAPIClusterNameLabel: c.ClusterName})
+		}
+	case s.output.profile != "":
+		// other profiles write the files of a seed, NoCloud by default
+		if sd == nil {
+			sd = noCloudSeed(&v1alpha1.NoCloudSeed{}, spec.ForProvider.NetworkConfig)
+		}
+		s.seed = sd
+		s.output.key = profileUserDataKeys[s.output.profile]
+	case s.output.format == v1alpha1.OutputFormatIgnition:
+		// Ignition configs replace the user-data, and hold no seed
+		if ref.Key == "" {
+			s.output.key = ignitionKey
+		}
+	case s.output.format == v1alpha1.OutputFormatISO9660 || s.output.format == v1alpha1.OutputFormatTar:
+		// images and archives always hold a seed, written to the key
+		if sd == nil {
+			sd = noCloudSeed(&v1alpha1.NoCloudSeed{}, spec.ForProvider.NetworkConfig)
+		}
+		s.seed = sd
+		if ref.Key == "" {
+			s.output.key = sd.defaultKey(s.output.format)
+		}
+	case sd != nil:
+		s.seed = sd
+		s.output.key = sd.defaultKey(s.output.format)
+	}
+	return s, nil
+}
+
+// mimeBoundary returns the boundary to render the supplied parts with. An
+// empty boundary lets the renderer choose a random one. Images and archives
+// are always derived from the parts when no boundary is set, so that the same
+// seed produces the same bytes.
+func (s renderSettings) mimeBoundary(parts []cloudinit.PartReader) string {
+	image := s.output.format == v1alpha1.OutputFormatISO9660 || s.output.format == v1alpha1.OutputFormatTar
+	if s.boundary != "" || (s.boundaryStrategy != apisv1alpha1.BoundaryStrategyContentHash && !image) {
+		return s.boundary
+	}
+	return "MIMEBOUNDARY-" + partsDigest(parts)[:32]
+}
+
+// partsDigest returns the hex encoded digest of the headers and content of
+// parts, which unlike the rendered user-data does not depend on the boundary
+func partsDigest(parts []cloudinit.PartReader) string {
+	h := sha256.New()
+	for _, p := range parts {
+		for _, v := range []string{p.Filename(), p.ContentType(), p.MergeType(), p.Content()} {
+			_, _ = h.Write([]byte(v))
+			_, _ = h.Write([]byte{0})
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func mergeLabels(base, over map[string]string) map[string]string {
+	if len(over) == 0 {
+		return base
+	}
+	out := make(map[string]string, len(base)+len(over))
+	for k, v := range base {
+		out[k] = v
+	}
+	for k, v := range over {
+		out[k] = v
+	}
+	return out
+}
+
+func boolValue(b *bool) bool {
+	return b != nil && *b
+}
@@ -0,0 +1,155 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	v1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/config/v1alpha1"
+	apisv1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/v1alpha1"
+	clients "github.com/crossplane-contrib/provider-cloudinit/internal/clients"
+	"github.com/crossplane-contrib/provider-cloudinit/internal/clients/oci"
+)
+
+// artifactPuller pulls the content of a file from an OCI artifact
+type artifactPuller interface {
+	Pull(ctx context.Context, req oci.Request) ([]byte, error)
+}
+
+// partContent returns the content of a part, reading it from the part's
+// source when one is set. found is false when an optional source is missing.
+// Sources are read with the supplied client, and must be allowed by the
+// supplied policy unless it is nil.
+func (e *ctrlClients) partContent(ctx context.Context, kube client.Client, p v1alpha1.PartSpec, sp *apisv1alpha1.SourcePolicy) (content string, found bool, err error) {
+	// TODO(displague) p.SecretKeyRef and ConfigMapKeyRef should be set exclusively
+	switch {
+	case p.SecretKeyRef != nil:
+		return e.secretContent(ctx, kube, p.SecretKeyRef, sp)
+	case p.ConfigMapKeyRef != nil:
+		return e.configMapContent(ctx, kube, p.ConfigMapKeyRef, sp)
+	case p.OCIArtifactRef != nil:
+		return e.artifactContent(ctx, kube, p.OCIArtifactRef, sp)
+	}
+	return p.Content, true, nil
+}
+
+func (e *ctrlClients) secretContent(ctx context.Context, kube client.Client, ref *v1alpha1.DataKeySelector, sp *apisv1alpha1.SourcePolicy) (string, bool, error) {
+	if err := e.authorizeSource(ctx, sp, "Secret", ref.Namespace); err != nil {
+		return "", false, err
+	}
+	partSec := &corev1.Secret{}
+	partNsn := types.NamespacedName{
+		Name:      ref.Name,
+		Namespace: ref.Namespace,
+	}
+	if err := kube.Get(ctx, partNsn, partSec); err != nil {
+		if ref.Optional && clients.IsErrorNotFound(err) {
+			return "", false, nil
+		}
+		return "", false, errors.Wrap(err, errGetPart)
+	}
+	if err := e.authorizeSourceObject(sp, "Secret", partSec); err != nil {
+		return "", false, err
+	}
+	if partSec.Type != corev1.SecretTypeOpaque {
+		return "", false, errors.New(errOpaqueSecret)
+	}
+	key := ref.Key
+	if key == "" {
+		// TODO(displague) use default key, or use first key in secret?
+		key = configMapKey
+	}
+	return string(partSec.Data[key]), true, nil
+}
+
+func (e *ctrlClients) configMapContent(ctx context.Context, kube client.Client, ref *v1alpha1.DataKeySelector, sp *apisv1alpha1.SourcePolicy) (string, bool, error) {
+	if err := e.authorizeSource(ctx, sp, "ConfigMap", ref.Namespace); err != nil {
+		return "", false, err
+	}
+	partCM := &corev1.ConfigMap{}
+	partNsn := types.NamespacedName{
+		Name:      ref.Name,
+		Namespace: ref.Namespace,
+	}
+	if err := kube.Get(ctx, partNsn, partCM); err != nil {
+		if ref.Optional && clients.IsErrorNotFound(err) {
+			return "", false, nil
+		}
+		return "", false, errors.Wrap(err, errGetPart)
+	}
+	if err := e.authorizeSourceObject(sp, "ConfigMap", partCM); err != nil {
+		return "", false, err
+	}
+	key := ref.Key
+	if key == "" {
+		// TODO(displague) use default key, or use first key in configmap?
+		key = configMapKey
+	}
+	// TODO(displague) support binary configmap keys
+	return partCM.Data[key], true, nil
+}
+
+func (e *ctrlClients) artifactContent(ctx context.Context, kube client.Client, ref *v1alpha1.OCIArtifactSelector, sp *apisv1alpha1.SourcePolicy) (string, bool, error) {
+	image, err := oci.ParseReference(ref.Image)
+	if err != nil {
+		return "", false, errors.Wrap(err, errPullArtifact)
+	}
+	req := oci.Request{
+		Reference: image,
+		Digest:    ref.Digest,
+		MediaType: ref.MediaType,
+		Path:      ref.Path,
+		Insecure:  ref.Insecure,
+	}
+
+	if ref.PullSecretRef != nil {
+		if err := e.authorizeSource(ctx, sp, "Secret", ref.PullSecretRef.Namespace); err != nil {
+			return "", false, err
+		}
+		sec := &corev1.Secret{}
+		nsn := types.NamespacedName{Name: ref.PullSecretRef.Name, Namespace: ref.PullSecretRef.Namespace}
+		if err := kube.Get(ctx, nsn, sec); err != nil {
+			return "", false, errors.Wrap(err, errGetPullSecret)
+		}
+		if err := e.authorizeSourceObject(sp, "Secret", sec); err != nil {
+			return "", false, err
+		}
+		if sec.Type != corev1.SecretTypeDockerConfigJson {
+			return "", false, errors.New(errPullSecretType)
+		}
+		req.Credentials, err = oci.CredentialsFromDockerConfigJSON(sec.Data[corev1.DockerConfigJsonKey], image.Registry)
+		if err != nil {
+			return "", false, errors.Wrap(err, errGetPullSecret)
+		}
+	}
+
+	b, err := e.oci.Pull(ctx, req)
+	if err != nil {
+		// only a missing artifact is skipped; a pinned artifact that cannot
+		// be verified, or one the registry refuses or fails to serve, is not
+		if ref.Optional && ref.Digest == "" && image.Digest == "" && oci.IsNotFound(err) {
+			return "", false, nil
+		}
+		return "", false, errors.Wrap(err, errPullArtifact)
+	}
+	return string(b), true, nil
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+
+	v1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/config/v1alpha1"
+	"github.com/crossplane-contrib/provider-cloudinit/internal/clients/oci"
+)
+
+// A fakePuller returns content, or err when set
+type fakePuller struct {
+	content string
+	err     error
+}
+
+func (p *fakePuller) Pull(_ context.Context, _ oci.Request) ([]byte, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+	return []byte(p.content), nil
+}
+
+// errNotFound is known to the registry client as a missing artifact
+type errNotFound struct{ error }
+
+func (errNotFound) NotFound() bool { return true }
+
+func TestArtifactContent(t *testing.T) {
+	const pin = "sha256:0f1e2d3c4b5a69788796a5b4c3d2e1f00f1e2d3c4b5a69788796a5b4c3d2e1f0"
+	notFound := errNotFound{errors.New("GET manifests/v1: 404 Not Found")}
+	unauthorized := errors.New("GET manifests/v1: 401 Unauthorized")
+
+	cases := map[string]struct {
+		ref       v1alpha1.OCIArtifactSelector
+		puller    *fakePuller
+		want      string
+		wantFound bool
+		wantErr   bool
+	}{
+		"Pulled": {
+			ref:       v1alpha1.OCIArtifactSelector{Image: "registry.example.com/parts:v1"},
+			puller:    &fakePuller{content: "#cloud-config\n"},
+			want:      "#cloud-config\n",
+			wantFound: true,
+		},
+		"OptionalNotFound": {
+			ref:    v1alpha1.OCIArtifactSelector{Image: "registry.example.com/parts:v1", Optional: true},
+			puller: &fakePuller{err: notFound},
+		},
+		"OptionalUnauthorized": {
+			ref:     v1alpha1.OCIArtifactSelector{Image: "registry.example.com/parts:v1", Optional: true},
+			puller:  &fakePuller{err: unauthorized},
+			wantErr: true,
+		},
+		"OptionalPinnedNotFound": {
+			ref:     v1alpha1.OCIArtifactSelector{Image: "registry.example.com/parts:v1", Digest: pin, Optional: true},
+			puller:  &fakePuller{err: notFound},
+			wantErr: true,
+		},
+		"RequiredNotFound": {
+			ref:     v1alpha1.OCIArtifactSelector{Image: "registry.example.com/parts:v1"},
+			puller:  &fakePuller{err: notFound},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := newTestClients(t)
+			e.oci = tc.puller
+			got, found, err := e.artifactContent(context.Background(), e.kube, &tc.ref, nil)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("artifactContent(...): want error %t, got %v", tc.wantErr, err)
+			}
+			if found != tc.wantFound {
+				t.Errorf("artifactContent(...): want found %t, got %t", tc.wantFound, found)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("artifactContent(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
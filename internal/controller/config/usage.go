@@ -0,0 +1,84 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	xpv1 "github.com/crossplane/crossplane-runtime/apis/common/v1"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	v1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/config/v1alpha1"
+	apisv1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/v1alpha1"
+	clients "github.com/crossplane-contrib/provider-cloudinit/internal/clients"
+)
+
+const (
+	errApplyUsage  = "cannot apply ProviderConfigUsage"
+	errDeleteUsage = "cannot delete ProviderConfigUsage"
+)
+
+// A usageUntracker deletes the ProviderConfig usage of a managed resource
+type usageUntracker interface {
+	Untrack(ctx context.Context, mg resource.Managed) error
+}
+
+// A namespacedUsageTracker tracks the ProviderConfig usage of
+// NamespacedConfigs. ProviderConfigUsages are cluster scoped, so they cannot
+// be owned and garbage collected with a NamespacedConfig. They record its
+// namespace in a label instead, and are deleted once its output is.
+type namespacedUsageTracker struct {
+	kube client.Client
+}
+
+// Track creates or updates the ProviderConfigUsage of mg
+func (t *namespacedUsageTracker) Track(ctx context.Context, mg resource.Managed) error {
+	ref := mg.GetProviderConfigReference()
+	pcu := &apisv1alpha1.ProviderConfigUsage{}
+	pcu.SetName(string(mg.GetUID()))
+	pcu.SetLabels(map[string]string{
+		xpv1.LabelKeyProviderName:          ref.Name,
+		v1alpha1.LabelKeyResourceNamespace: mg.GetNamespace(),
+	})
+	pcu.SetProviderConfigReference(xpv1.Reference{Name: ref.Name})
+	pcu.SetResourceReference(xpv1.TypedReference{
+		APIVersion: v1alpha1.SchemeGroupVersion.String(),
+		Kind:       v1alpha1.NamespacedConfigKind,
+		Name:       mg.GetName(),
+		UID:        mg.GetUID(),
+	})
+	err := resource.NewAPIUpdatingApplicator(t.kube).Apply(ctx, pcu,
+		resource.AllowUpdateIf(func(current, _ runtime.Object) bool {
+			c := current.(*apisv1alpha1.ProviderConfigUsage)
+			return c.GetProviderConfigReference() != pcu.GetProviderConfigReference() ||
+				c.GetLabels()[v1alpha1.LabelKeyResourceNamespace] != mg.GetNamespace()
+		}),
+	)
+	return errors.Wrap(resource.Ignore(resource.IsNotAllowed, err), errApplyUsage)
+}
+
+// Untrack deletes the ProviderConfigUsage of mg
+func (t *namespacedUsageTracker) Untrack(ctx context.Context, mg resource.Managed) error {
+	pcu := &apisv1alpha1.ProviderConfigUsage{}
+	pcu.SetName(string(mg.GetUID()))
+	err := t.kube.Delete(ctx, pcu)
+	return errors.Wrap(resource.Ignore(clients.IsErrorNotFound, err), errDeleteUsage)
+}
@@ -0,0 +1,501 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime/multipart"
+	"net"
+	"net/http"
+
+	"github.com/pkg/errors"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/conversion"
+	"sigs.k8s.io/yaml"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/pkg/resource"
+
+	v1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/config/v1alpha1"
+	apisv1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/v1alpha1"
+	clients "github.com/crossplane-contrib/provider-cloudinit/internal/clients"
+	"github.com/crossplane-contrib/provider-cloudinit/internal/clients/oci"
+	"github.com/crossplane-contrib/provider-cloudinit/internal/cloudinit"
+	"github.com/crossplane-contrib/provider-cloudinit/internal/ignition"
+)
+
+// Webhook paths, following the controller-runtime conventions.
+const (
+	ValidateConfigPath           = "/validate-cloudinit-crossplane-io-v1alpha1-config"
+	ValidateNamespacedConfigPath = "/validate-cloudinit-crossplane-io-v1alpha1-namespacedconfig"
+	MutateConfigPath             = "/mutate-cloudinit-crossplane-io-v1alpha1-config"
+	MutateNamespacedConfigPath   = "/mutate-cloudinit-crossplane-io-v1alpha1-namespacedconfig"
+	ConvertPath                  = "/convert"
+)
+
+// SetupWebhook registers the validating, defaulting and conversion webhooks
+// for Config and NamespacedConfig with the webhook server of the manager.
+func SetupWebhook(mgr ctrl.Manager, l logging.Logger) error {
+	v := &validator{kube: mgr.GetClient(), log: l.WithValues("webhook", "validate")}
+	d := &defaulter{kube: mgr.GetClient()}
+	mgr.GetWebhookServer().Register(ValidateConfigPath, &webhook.Admission{Handler: v})
+	mgr.GetWebhookServer().Register(ValidateNamespacedConfigPath, &webhook.Admission{Handler: v})
+	mgr.GetWebhookServer().Register(MutateConfigPath, &webhook.Admission{Handler: d})
+	mgr.GetWebhookServer().Register(MutateNamespacedConfigPath, &webhook.Admission{Handler: d})
+	mgr.GetWebhookServer().Register(ConvertPath, &conversion.Webhook{})
+	return nil
+}
+
+// validator rejects Configs that cannot be rendered
+type validator struct {
+	kube    client.Client
+	log     logging.Logger
+	decoder *admission.Decoder
+}
+
+// InjectDecoder injects the decoder of admission requests.
+func (v *validator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+// Handle validates a Config or NamespacedConfig being created or updated.
+func (v *validator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return admission.Allowed("")
+	}
+	mg, err := decode(v.decoder, req, req.Object)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if mg.GetDeletionTimestamp() != nil {
+		return admission.Allowed("")
+	}
+	spec, _, cerr := configOf(mg)
+	if req.Operation == admissionv1.Update {
+		// existing objects may be updated, e.g. by the reconciler, as
+		// long as their spec does not change
+		old, err := decode(v.decoder, req, req.OldObject)
+		if err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		oldSpec, _, _ := configOf(old)
+		if equality.Semantic.DeepEqual(spec, oldSpec) {
+			return admission.Allowed("")
+		}
+	}
+
+	pc, err := providerConfig(ctx, v.kube, mg)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	errs := validateSpec(spec, pc)
+	if cerr != nil {
+		errs = append(errs, field.Forbidden(field.NewPath("spec"), cerr.Error()))
+	}
+	if pc != nil {
+		e := &ctrlClients{kube: v.kube, pc: pc}
+		if err := e.authorizeConsumer(ctx, mg); err != nil {
+			if _, denied := isAccessDenied(err); !denied {
+				return admission.Errored(http.StatusInternalServerError, err)
+			}
+			errs = append(errs, field.Forbidden(field.NewPath("spec", "providerConfigRef"), err.Error()))
+		}
+		if _, err := serviceAccountOf(mg, spec, pc); err != nil {
+			if _, denied := isAccessDenied(err); denied {
+				errs = append(errs, field.Forbidden(field.NewPath("spec", "forProvider", "serviceAccountRef"), err.Error()))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		v.log.Debug("Rejected invalid spec", "kind", req.Kind.Kind, "name", req.Name, "namespace", req.Namespace, "errors", errs.ToAggregate().Error())
+		return admission.Denied(errs.ToAggregate().Error())
+	}
+	return admission.Allowed("")
+}
+
+func decode(d *admission.Decoder, req admission.Request, raw runtime.RawExtension) (resource.Managed, error) {
+	var mg resource.Managed
+	switch req.Kind.Kind {
+	case v1alpha1.ConfigKind:
+		mg = &v1alpha1.Config{}
+	case v1alpha1.NamespacedConfigKind:
+		mg = &v1alpha1.NamespacedConfig{}
+	default:
+		return nil, errors.New(errNotConfig)
+	}
+	return mg, d.DecodeRaw(raw, mg)
+}
+
+// providerConfig returns the ProviderConfig of mg, or nil when it does not
+// exist yet. Settings that depend on it are validated when rendering.
+func providerConfig(ctx context.Context, kube client.Client, mg resource.Managed) (*apisv1alpha1.ProviderConfig, error) {
+	pc, err := GetProviderConfig(ctx, kube, mg)
+	if clients.IsErrorNotFound(err) {
+		return nil, nil
+	}
+	return pc, err
+}
+
+// validateSpec runs the checks the renderer would, without reading sources
+func validateSpec(spec *v1alpha1.ConfigSpec, pc *apisv1alpha1.ProviderConfig) field.ErrorList {
+	errs := field.ErrorList{}
+	sp := field.NewPath("spec")
+
+	out := sp.Child("writeCloudInitToRef")
+	switch {
+	case spec.WriteCloudInitToRef == nil:
+		errs = append(errs, field.Required(out, ""))
+	case spec.WriteCloudInitToRef.Name == "":
+		errs = append(errs, field.Required(out.Child("name"), ""))
+	}
+
+	// the output reference is checked above
+	s, _ := newRenderSettings(spec, pc)
+	if s.gzip && !s.base64Encode {
+		errs = append(errs, field.Invalid(sp.Child("forProvider", "gzip"), true, "base64Encode is mandatory when gzip is enabled"))
+	}
+	if b := spec.ForProvider.Boundary; b != "" {
+		if err := multipart.NewWriter(ioutil.Discard).SetBoundary(b); err != nil {
+			errs = append(errs, field.Invalid(sp.Child("forProvider", "boundary"), b, err.Error()))
+		}
+	}
+
+	pp := sp.Child("forProvider", "parts")
+	if len(spec.ForProvider.Parts)+len(s.prepend)+len(s.append) == 0 {
+		errs = append(errs, field.Required(pp, "at least one part is required"))
+	}
+	for i, p := range spec.ForProvider.Parts {
+		errs = append(errs, validatePart(pp.Index(i), p, spec.ForProvider.Target)...)
+	}
+	if err := checkBaselineFilenames(spec.ForProvider.Parts, s); err != nil {
+		errs = append(errs, field.Forbidden(pp, err.Error()))
+	}
+	errs = append(errs, validateSeed(sp, spec, s)...)
+	if ref := spec.WriteCloudInitToRef; ref != nil && ref.Profile != "" {
+		errs = append(errs, validateProfile(sp, spec, s)...)
+	}
+	if ref := spec.WriteCloudInitToRef; ref != nil && ref.Format == v1alpha1.OutputFormatIgnition {
+		errs = append(errs, validateIgnition(sp, spec)...)
+	}
+	return errs
+}
+
+// validateIgnition checks that the parts of a Config written as Ignition can
+// be translated. The content of sources is not known before rendering, only
+// their declared type.
+func validateIgnition(sp *field.Path, spec *v1alpha1.ConfigSpec) field.ErrorList {
+	errs := field.ErrorList{}
+	p := spec.ForProvider
+	if p.NoCloud != nil || p.ConfigDrive != nil {
+		errs = append(errs, field.Forbidden(sp.Child("forProvider"), "noCloud and configDrive are not written with the Ignition format"))
+	}
+	if p.Target == v1alpha1.TargetCloudbaseInit {
+		errs = append(errs, field.Forbidden(sp.Child("forProvider", "target"), "cloudbase-init does not read Ignition"))
+	}
+	pp := sp.Child("forProvider", "parts")
+	for i, part := range p.Parts {
+		if part.ConfigMapKeyRef != nil || part.SecretKeyRef != nil || part.OCIArtifactRef != nil {
+			if part.ContentType != "" && part.ContentType != cloudinit.ContentTypePlain && part.ContentType != cloudinit.ContentTypeCloudConfig {
+				errs = append(errs, field.NotSupported(pp.Index(i).Child("contentType"), part.ContentType, []string{cloudinit.ContentTypeCloudConfig}))
+			}
+			continue
+		}
+		if err := ignition.CheckPart(part.ContentType, part.Content); err != nil {
+			errs = append(errs, field.Invalid(pp.Index(i).Child("content"), cloudinit.ContentTypeOf(part.ContentType, part.Content), err.Error()))
+		}
+	}
+	return errs
+}
+
+// validateProfile checks that the output can be read by the consumer its
+// profile writes for
+func validateProfile(sp *field.Path, spec *v1alpha1.ConfigSpec, s renderSettings) field.ErrorList {
+	errs := field.ErrorList{}
+	ref, out := spec.WriteCloudInitToRef, sp.Child("writeCloudInitToRef")
+	if err := s.checkProfileOutput(); err != nil {
+		errs = append(errs, field.Forbidden(out.Child("profile"), err.Error()))
+	}
+	if ref.Key != "" && ref.Key != s.output.key {
+		errs = append(errs, field.Invalid(out.Child("key"), ref.Key, fmt.Sprintf("must be %s with the %s profile", s.output.key, ref.Profile)))
+	}
+	if ref.Format != "" && ref.Format != v1alpha1.OutputFormatKeys {
+		errs = append(errs, field.Invalid(out.Child("format"), ref.Format, fmt.Sprintf("must be Keys with the %s profile", ref.Profile)))
+	}
+	switch ref.Profile {
+	case v1alpha1.OutputProfileKubeVirt:
+		errs = append(errs, validateKubeVirt(sp, spec)...)
+	case v1alpha1.OutputProfileClusterAPI:
+		if ref.ClusterAPI == nil || ref.ClusterAPI.ClusterName == "" {
+			errs = append(errs, field.Required(out.Child("clusterAPI", "clusterName"), ""))
+		}
+		p := spec.ForProvider
+		if p.NoCloud != nil || p.ConfigDrive != nil || p.NetworkConfig != nil {
+			errs = append(errs, field.Forbidden(sp.Child("forProvider"), "noCloud, configDrive and networkConfig are not written with the ClusterAPI profile"))
+		}
+	case v1alpha1.OutputProfileVMwareGuestInfo:
+		if spec.ForProvider.ConfigDrive != nil {
+			errs = append(errs, field.Forbidden(sp.Child("forProvider", "configDrive"), "guestinfo holds a NoCloud seed"))
+		}
+	case v1alpha1.OutputProfileLXD:
+		p := spec.ForProvider
+		if p.ConfigDrive != nil {
+			errs = append(errs, field.Forbidden(sp.Child("forProvider", "configDrive"), "LXD reads a NoCloud seed"))
+		}
+		if p.NoCloud != nil && p.NoCloud.MetaData != (v1alpha1.NoCloudMetaData{}) {
+			errs = append(errs, field.Forbidden(sp.Child("forProvider", "noCloud", "metaData"), "LXD generates meta-data"))
+		}
+	}
+	return errs
+}
+
+// validateKubeVirt checks that the seed settings can be read by KubeVirt,
+// which generates meta-data itself
+func validateKubeVirt(sp *field.Path, spec *v1alpha1.ConfigSpec) field.ErrorList {
+	errs := field.ErrorList{}
+	p := spec.ForProvider
+	if nc := p.NoCloud; nc != nil && (nc.MetaData != (v1alpha1.NoCloudMetaData{}) || nc.VendorData != "") {
+		errs = append(errs, field.Forbidden(sp.Child("forProvider", "noCloud"), "only networkConfig is written with the KubeVirt profile, which generates meta-data"))
+	}
+	if cd := p.ConfigDrive; cd != nil && !equality.Semantic.DeepEqual(cd.MetaData, v1alpha1.ConfigDriveMetaData{}) {
+		errs = append(errs, field.Forbidden(sp.Child("forProvider", "configDrive", "metaData"), "KubeVirt generates meta-data"))
+	}
+	return errs
+}
+
+// validateSeed checks the NoCloud seed or config drive written to the output
+func validateSeed(sp *field.Path, spec *v1alpha1.ConfigSpec, s renderSettings) field.ErrorList {
+	errs := field.ErrorList{}
+	p := spec.ForProvider
+	if p.NoCloud != nil && p.ConfigDrive != nil {
+		errs = append(errs, field.Forbidden(sp.Child("forProvider", "configDrive"), "only one of noCloud and configDrive may be set"))
+	}
+	ref := spec.WriteCloudInitToRef
+	if ref != nil && ref.Profile == "" && s.seed != nil && s.output.format == v1alpha1.OutputFormatKeys && ref.Key != "" && ref.Key != s.output.key {
+		errs = append(errs, field.Invalid(sp.Child("writeCloudInitToRef", "key"), ref.Key, "must be "+s.output.key+" when the seed is written as keys"))
+	}
+	if p.NoCloud != nil {
+		errs = append(errs, validateNoCloud(sp, p.NoCloud)...)
+	}
+	if nc := p.NetworkConfig; nc != nil {
+		np := sp.Child("forProvider", "networkConfig")
+		switch {
+		case s.seed == nil || p.ConfigDrive != nil:
+			errs = append(errs, field.Forbidden(np, "requires a NoCloud seed"))
+		case p.NoCloud != nil && p.NoCloud.NetworkConfig != "":
+			errs = append(errs, field.Forbidden(np, "may not be set with noCloud.networkConfig"))
+		}
+		errs = append(errs, validateNetworkConfig(np, nc)...)
+	}
+	if cd := p.ConfigDrive; cd != nil && cd.NetworkData != "" && !json.Valid([]byte(cd.NetworkData)) {
+		errs = append(errs, field.Invalid(sp.Child("forProvider", "configDrive", "networkData"), cd.NetworkData, "must be a JSON document"))
+	}
+	return errs
+}
+
+func validateNoCloud(sp *field.Path, seed *v1alpha1.NoCloudSeed) field.ErrorList {
+	errs := field.ErrorList{}
+	if seed.NetworkConfig != "" {
+		nc := map[string]interface{}{}
+		if err := yaml.Unmarshal([]byte(seed.NetworkConfig), &nc); err != nil {
+			errs = append(errs, field.Invalid(sp.Child("forProvider", "noCloud", "networkConfig"), seed.NetworkConfig, err.Error()))
+		}
+	}
+	return errs
+}
+
+// validateNetworkConfig checks the addresses of a typed network
+// configuration, and that the interfaces it refers to are defined
+func validateNetworkConfig(np *field.Path, nc *v1alpha1.NetworkConfig) field.ErrorList {
+	errs := field.ErrorList{}
+	ids := map[string]bool{}
+	for id := range nc.Ethernets {
+		ids[id] = true
+	}
+	define := func(p *field.Path, id string) {
+		if ids[id] {
+			errs = append(errs, field.Duplicate(p, id))
+		}
+		ids[id] = true
+	}
+	for id := range nc.Bonds {
+		define(np.Child("bonds"), id)
+	}
+	for id := range nc.VLANs {
+		define(np.Child("vlans"), id)
+	}
+	for id := range nc.Bridges {
+		define(np.Child("bridges"), id)
+	}
+	refs := func(p *field.Path, interfaces []string) {
+		for i, id := range interfaces {
+			if !ids[id] {
+				errs = append(errs, field.NotFound(p.Index(i), id))
+			}
+		}
+	}
+
+	for id, e := range nc.Ethernets {
+		p := np.Child("ethernets").Key(id)
+		errs = append(errs, validateInterface(p, e.InterfaceSettings)...)
+		if e.SetName != "" && e.Match == nil {
+			errs = append(errs, field.Required(p.Child("match"), "setName requires match"))
+		}
+		if m := e.Match; m != nil && m.MACAddress != "" {
+			if _, err := net.ParseMAC(m.MACAddress); err != nil {
+				errs = append(errs, field.Invalid(p.Child("match", "macAddress"), m.MACAddress, err.Error()))
+			}
+		}
+	}
+	for id, b := range nc.Bonds {
+		p := np.Child("bonds").Key(id)
+		errs = append(errs, validateInterface(p, b.InterfaceSettings)...)
+		refs(p.Child("interfaces"), b.Interfaces)
+	}
+	for id, v := range nc.VLANs {
+		p := np.Child("vlans").Key(id)
+		errs = append(errs, validateInterface(p, v.InterfaceSettings)...)
+		if !ids[v.Link] {
+			errs = append(errs, field.NotFound(p.Child("link"), v.Link))
+		}
+	}
+	for id, b := range nc.Bridges {
+		p := np.Child("bridges").Key(id)
+		errs = append(errs, validateInterface(p, b.InterfaceSettings)...)
+		refs(p.Child("interfaces"), b.Interfaces)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	if _, err := interfaceOrder(nc); err != nil {
+		errs = append(errs, field.Forbidden(np, err.Error()))
+	}
+	if len(errs) == 0 && nc.Version == 1 {
+		if _, err := version1(nc); err != nil {
+			errs = append(errs, field.Invalid(np.Child("version"), nc.Version, err.Error()))
+		}
+	}
+	return errs
+}
+
+func validateInterface(p *field.Path, s v1alpha1.InterfaceSettings) field.ErrorList {
+	errs := field.ErrorList{}
+	families := map[bool]bool{}
+	for i, a := range s.Addresses {
+		ip, _, err := net.ParseCIDR(a)
+		if err != nil {
+			errs = append(errs, field.Invalid(p.Child("addresses").Index(i), a, "must be an address in CIDR notation"))
+			continue
+		}
+		families[ip.To4() == nil] = true
+	}
+	gateway := func(name, gw string, v6 bool) {
+		ip := net.ParseIP(gw)
+		switch {
+		case gw == "":
+		case ip == nil || (ip.To4() == nil) != v6:
+			errs = append(errs, field.Invalid(p.Child(name), gw, "must be an IP address of its family"))
+		case !families[v6]:
+			errs = append(errs, field.Forbidden(p.Child(name), "requires a static address of its family"))
+		}
+	}
+	gateway("gateway4", s.Gateway4, false)
+	gateway("gateway6", s.Gateway6, true)
+	if ns := s.Nameservers; ns != nil {
+		for i, a := range ns.Addresses {
+			if net.ParseIP(a) == nil {
+				errs = append(errs, field.Invalid(p.Child("nameservers", "addresses").Index(i), a, "must be an IP address"))
+			}
+		}
+	}
+	for i, r := range s.Routes {
+		rp := p.Child("routes").Index(i)
+		if _, _, err := net.ParseCIDR(r.To); err != nil && r.To != "default" {
+			errs = append(errs, field.Invalid(rp.Child("to"), r.To, "must be a destination in CIDR notation, or default"))
+		}
+		if net.ParseIP(r.Via) == nil {
+			errs = append(errs, field.Invalid(rp.Child("via"), r.Via, "must be an IP address"))
+		}
+	}
+	return errs
+}
+
+// validateWindowsPart checks that cloudbase-init handles a part. The content
+// of sources is not known before rendering, only their declared type.
+func validateWindowsPart(path *field.Path, p v1alpha1.PartSpec, sources int) field.ErrorList {
+	errs := field.ErrorList{}
+	if sources > 0 {
+		if p.ContentType != "" && p.ContentType != cloudinit.ContentTypePlain && !cloudinit.IsWindowsContentType(p.ContentType) {
+			errs = append(errs, field.NotSupported(path.Child("contentType"), p.ContentType, cloudinit.WindowsContentTypes()))
+		}
+		return errs
+	}
+	ct := cloudinit.WindowsContentTypeOf(p.ContentType, p.Content)
+	for _, err := range cloudinit.ValidateWindowsPart(p.ContentType, p.Content) {
+		errs = append(errs, field.Invalid(path.Child("content"), ct, err.Error()))
+	}
+	return errs
+}
+
+func validatePart(path *field.Path, p v1alpha1.PartSpec, target v1alpha1.Target) field.ErrorList {
+	errs := field.ErrorList{}
+	sources := 0
+	if p.ConfigMapKeyRef != nil {
+		sources++
+		if p.ConfigMapKeyRef.Name == "" {
+			errs = append(errs, field.Required(path.Child("configMapKeyRef", "name"), ""))
+		}
+	}
+	if p.SecretKeyRef != nil {
+		sources++
+		if p.SecretKeyRef.Name == "" {
+			errs = append(errs, field.Required(path.Child("secretKeyRef", "name"), ""))
+		}
+	}
+	if p.OCIArtifactRef != nil {
+		sources++
+		if _, err := oci.ParseReference(p.OCIArtifactRef.Image); err != nil {
+			errs = append(errs, field.Invalid(path.Child("ociArtifactRef", "image"), p.OCIArtifactRef.Image, err.Error()))
+		}
+	}
+
+	switch {
+	case sources > 1:
+		errs = append(errs, field.Forbidden(path, "only one of configMapKeyRef, secretKeyRef and ociArtifactRef may be set"))
+	case target == v1alpha1.TargetCloudbaseInit:
+		errs = append(errs, validateWindowsPart(path, p, sources)...)
+	case sources == 0:
+		// only inline content is known before rendering
+		if ct := cloudinit.ContentTypeOf(p.ContentType, p.Content); ct == cloudinit.ContentTypeCloudConfig {
+			for _, err := range cloudinit.ValidateCloudConfig(p.Content) {
+				errs = append(errs, field.Invalid(path.Child("content"), ct, err.Error()))
+			}
+		}
+	}
+	return errs
+}
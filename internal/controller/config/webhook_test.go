@@ -0,0 +1,463 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/google/go-cmp/cmp"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"github.com/crossplane/crossplane-runtime/pkg/logging"
+
+	v1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/config/v1alpha1"
+	apisv1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/v1alpha1"
+)
+
+// newAdmissionRequest returns a request to create obj, or to update old to
+// obj when old is not nil
+func newAdmissionRequest(t *testing.T, obj, old runtime.Object) admission.Request {
+	t.Helper()
+	req := admission.Request{}
+	req.Operation = admissionv1.Create
+	req.Kind.Kind = v1alpha1.ConfigKind
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Object.Raw = raw
+	if old != nil {
+		req.Operation = admissionv1.Update
+		if req.OldObject.Raw, err = json.Marshal(old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return req
+}
+
+// newTestWebhooks returns the validating and defaulting webhooks, reading
+// ProviderConfigs from a fake API server holding none
+func newTestWebhooks(t *testing.T) (*validator, *defaulter) {
+	t.Helper()
+	s := newTestScheme(t)
+	dec, err := admission.NewDecoder(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kube := fake.NewClientBuilder().WithScheme(s).Build()
+	return &validator{kube: kube, log: logging.NewNopLogger(), decoder: dec}, &defaulter{kube: kube, decoder: dec}
+}
+
+// defaulted returns the Config of req as the defaulting webhook patches it
+func defaulted(t *testing.T, d *defaulter, req admission.Request) *v1alpha1.Config {
+	t.Helper()
+	res := d.Handle(context.Background(), req)
+	if !res.Allowed {
+		t.Fatalf("defaulter.Handle(...): %s", res.Result.Message)
+	}
+	ops, err := json.Marshal(res.Patches)
+	if err != nil {
+		t.Fatal(err)
+	}
+	patch, err := jsonpatch.DecodePatch(ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := patch.Apply(req.Object.Raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cr := &v1alpha1.Config{}
+	if err := json.Unmarshal(raw, cr); err != nil {
+		t.Fatal(err)
+	}
+	return cr
+}
+
+func TestWebhookOutputChange(t *testing.T) {
+	cases := map[string]func(ref *v1alpha1.OutputSelector){
+		"Profile": func(ref *v1alpha1.OutputSelector) {
+			ref.Profile = v1alpha1.OutputProfileKubeVirt
+		},
+		"LXDProfile": func(ref *v1alpha1.OutputSelector) {
+			ref.Profile = v1alpha1.OutputProfileLXD
+		},
+		"Format": func(ref *v1alpha1.OutputSelector) {
+			ref.Format = v1alpha1.OutputFormatISO9660
+		},
+	}
+	for name, change := range cases {
+		t.Run(name, func(t *testing.T) {
+			v, d := newTestWebhooks(t)
+			created := defaulted(t, d, newAdmissionRequest(t, newTestConfig(), nil))
+
+			updated := created.DeepCopy()
+			change(updated.Spec.WriteCloudInitToRef)
+			updated = defaulted(t, d, newAdmissionRequest(t, updated, created))
+			if res := v.Handle(context.Background(), newAdmissionRequest(t, updated, created)); !res.Allowed {
+				t.Errorf("validator.Handle(...): %s", res.Result.Reason)
+			}
+			if key := updated.Spec.WriteCloudInitToRef.Key; key != "" {
+				t.Errorf("defaulter.Handle(...): want the output key left to the renderer, got %q", key)
+			}
+		})
+	}
+}
+
+// errorFields returns the field and type of each error of errs
+func errorFields(errs field.ErrorList) []string {
+	var out []string
+	for _, e := range errs {
+		out = append(out, e.Field+": "+string(e.Type))
+	}
+	return out
+}
+
+func TestValidateSpec(t *testing.T) {
+	enabled := true
+	cases := map[string]struct {
+		spec func(spec *v1alpha1.ConfigSpec)
+		pc   *apisv1alpha1.ProviderConfig
+		want []string
+	}{
+		"Valid": {
+			spec: func(spec *v1alpha1.ConfigSpec) {},
+		},
+		"OutputRequired": {
+			spec: func(spec *v1alpha1.ConfigSpec) { spec.WriteCloudInitToRef = nil },
+			want: []string{"spec.writeCloudInitToRef: FieldValueRequired"},
+		},
+		"OutputNameRequired": {
+			spec: func(spec *v1alpha1.ConfigSpec) { spec.WriteCloudInitToRef.Name = "" },
+			want: []string{"spec.writeCloudInitToRef.name: FieldValueRequired"},
+		},
+		"GzipWithoutBase64": {
+			spec: func(spec *v1alpha1.ConfigSpec) { spec.ForProvider.Gzip = &enabled },
+			want: []string{"spec.forProvider.gzip: FieldValueInvalid"},
+		},
+		"Boundary": {
+			spec: func(spec *v1alpha1.ConfigSpec) { spec.ForProvider.Boundary = "boundary!" },
+			want: []string{"spec.forProvider.boundary: FieldValueInvalid"},
+		},
+		"BoundaryLength": {
+			spec: func(spec *v1alpha1.ConfigSpec) { spec.ForProvider.Boundary = strings.Repeat("b", 71) },
+			want: []string{"spec.forProvider.boundary: FieldValueInvalid"},
+		},
+		"PartsRequired": {
+			spec: func(spec *v1alpha1.ConfigSpec) { spec.ForProvider.Parts = nil },
+			want: []string{"spec.forProvider.parts: FieldValueRequired"},
+		},
+		"BaselinePartsOnly": {
+			spec: func(spec *v1alpha1.ConfigSpec) { spec.ForProvider.Parts = nil },
+			pc: &apisv1alpha1.ProviderConfig{Spec: apisv1alpha1.ProviderConfigSpec{
+				BaselineParts: apisv1alpha1.BaselineParts{Prepend: []v1alpha1.PartSpec{{Content: "#cloud-config\n"}}},
+			}},
+		},
+		"BaselineFilename": {
+			spec: func(spec *v1alpha1.ConfigSpec) { spec.ForProvider.Parts[0].Filename = "part-001" },
+			pc: &apisv1alpha1.ProviderConfig{Spec: apisv1alpha1.ProviderConfigSpec{
+				BaselineParts: apisv1alpha1.BaselineParts{Append: []v1alpha1.PartSpec{{Content: "#cloud-config\n"}}},
+			}},
+			want: []string{"spec.forProvider.parts: FieldValueForbidden"},
+		},
+		"SeveralSources": {
+			spec: func(spec *v1alpha1.ConfigSpec) {
+				spec.ForProvider.Parts[0].ConfigMapKeyRef = &v1alpha1.DataKeySelector{NamespacedName: v1alpha1.NamespacedName{Name: "parts"}}
+				spec.ForProvider.Parts[0].SecretKeyRef = &v1alpha1.DataKeySelector{NamespacedName: v1alpha1.NamespacedName{Name: "parts"}}
+			},
+			want: []string{"spec.forProvider.parts[0]: FieldValueForbidden"},
+		},
+		"SourceNames": {
+			spec: func(spec *v1alpha1.ConfigSpec) {
+				spec.ForProvider.Parts = []v1alpha1.PartSpec{
+					{ContentFromSource: v1alpha1.ContentFromSource{ConfigMapKeyRef: &v1alpha1.DataKeySelector{}}},
+					{ContentFromSource: v1alpha1.ContentFromSource{SecretKeyRef: &v1alpha1.DataKeySelector{}}},
+				}
+			},
+			want: []string{
+				"spec.forProvider.parts[0].configMapKeyRef.name: FieldValueRequired",
+				"spec.forProvider.parts[1].secretKeyRef.name: FieldValueRequired",
+			},
+		},
+		"OCIImage": {
+			spec: func(spec *v1alpha1.ConfigSpec) {
+				spec.ForProvider.Parts[0].OCIArtifactRef = &v1alpha1.OCIArtifactSelector{Image: "registry.example.com/"}
+			},
+			want: []string{"spec.forProvider.parts[0].ociArtifactRef.image: FieldValueInvalid"},
+		},
+		"CloudConfigSchema": {
+			spec: func(spec *v1alpha1.ConfigSpec) { spec.ForProvider.Parts[0].Content = "#cloud-config\nruncmd: reboot\n" },
+			want: []string{"spec.forProvider.parts[0].content: FieldValueInvalid"},
+		},
+		"WindowsPart": {
+			spec: func(spec *v1alpha1.ConfigSpec) {
+				spec.ForProvider.Target = v1alpha1.TargetCloudbaseInit
+				spec.ForProvider.Parts[0].Content = "#cloud-config\npackages: [curl]\n"
+			},
+			want: []string{"spec.forProvider.parts[0].content: FieldValueInvalid"},
+		},
+		"NoCloudAndConfigDrive": {
+			spec: func(spec *v1alpha1.ConfigSpec) {
+				spec.ForProvider.NoCloud = &v1alpha1.NoCloudSeed{}
+				spec.ForProvider.ConfigDrive = &v1alpha1.ConfigDrive{}
+			},
+			want: []string{"spec.forProvider.configDrive: FieldValueForbidden"},
+		},
+		"SeedKey": {
+			spec: func(spec *v1alpha1.ConfigSpec) {
+				spec.ForProvider.NoCloud = &v1alpha1.NoCloudSeed{}
+				spec.WriteCloudInitToRef.Key = "custom"
+			},
+			want: []string{"spec.writeCloudInitToRef.key: FieldValueInvalid"},
+		},
+		"SeedImageKey": {
+			spec: func(spec *v1alpha1.ConfigSpec) {
+				spec.ForProvider.NoCloud = &v1alpha1.NoCloudSeed{}
+				spec.WriteCloudInitToRef.Format = v1alpha1.OutputFormatISO9660
+				spec.WriteCloudInitToRef.Key = "custom.iso"
+			},
+		},
+		"NoCloudNetworkConfig": {
+			spec: func(spec *v1alpha1.ConfigSpec) {
+				spec.ForProvider.NoCloud = &v1alpha1.NoCloudSeed{NetworkConfig: "version: [2"}
+			},
+			want: []string{"spec.forProvider.noCloud.networkConfig: FieldValueInvalid"},
+		},
+		"NetworkConfigWithoutSeed": {
+			spec: func(spec *v1alpha1.ConfigSpec) {
+				spec.ForProvider.NetworkConfig = &v1alpha1.NetworkConfig{Version: 2}
+			},
+			want: []string{"spec.forProvider.networkConfig: FieldValueForbidden"},
+		},
+		"NetworkConfigTwice": {
+			spec: func(spec *v1alpha1.ConfigSpec) {
+				spec.ForProvider.NoCloud = &v1alpha1.NoCloudSeed{NetworkConfig: "version: 2\n"}
+				spec.ForProvider.NetworkConfig = &v1alpha1.NetworkConfig{Version: 2}
+			},
+			want: []string{"spec.forProvider.networkConfig: FieldValueForbidden"},
+		},
+		"ConfigDriveNetworkData": {
+			spec: func(spec *v1alpha1.ConfigSpec) {
+				spec.ForProvider.ConfigDrive = &v1alpha1.ConfigDrive{NetworkData: "{"}
+			},
+			want: []string{"spec.forProvider.configDrive.networkData: FieldValueInvalid"},
+		},
+		"ProfileEncoding": {
+			spec: func(spec *v1alpha1.ConfigSpec) {
+				spec.WriteCloudInitToRef.Profile = v1alpha1.OutputProfileKubeVirt
+				spec.ForProvider.Base64Encode = &enabled
+			},
+			want: []string{"spec.writeCloudInitToRef.profile: FieldValueForbidden"},
+		},
+		"ProfileKind": {
+			spec: func(spec *v1alpha1.ConfigSpec) {
+				spec.WriteCloudInitToRef.Profile = v1alpha1.OutputProfileClusterAPI
+				spec.WriteCloudInitToRef.ClusterAPI = &v1alpha1.ClusterAPIOutput{ClusterName: "cluster"}
+				spec.WriteCloudInitToRef.Kind = v1alpha1.OutputKindConfigMap
+			},
+			want: []string{"spec.writeCloudInitToRef.profile: FieldValueForbidden"},
+		},
+		"ProfileKey": {
+			spec: func(spec *v1alpha1.ConfigSpec) {
+				spec.WriteCloudInitToRef.Profile = v1alpha1.OutputProfileKubeVirt
+				spec.WriteCloudInitToRef.Key = "custom"
+			},
+			want: []string{"spec.writeCloudInitToRef.key: FieldValueInvalid"},
+		},
+		"ProfileFormat": {
+			spec: func(spec *v1alpha1.ConfigSpec) {
+				spec.WriteCloudInitToRef.Profile = v1alpha1.OutputProfileLXD
+				spec.WriteCloudInitToRef.Format = v1alpha1.OutputFormatTar
+			},
+			want: []string{"spec.writeCloudInitToRef.format: FieldValueInvalid"},
+		},
+		"ClusterName": {
+			spec: func(spec *v1alpha1.ConfigSpec) {
+				spec.WriteCloudInitToRef.Profile = v1alpha1.OutputProfileClusterAPI
+			},
+			want: []string{"spec.writeCloudInitToRef.clusterAPI.clusterName: FieldValueRequired"},
+		},
+		"ClusterAPISeed": {
+			spec: func(spec *v1alpha1.ConfigSpec) {
+				spec.WriteCloudInitToRef.Profile = v1alpha1.OutputProfileClusterAPI
+				spec.WriteCloudInitToRef.ClusterAPI = &v1alpha1.ClusterAPIOutput{ClusterName: "cluster"}
+				spec.ForProvider.NoCloud = &v1alpha1.NoCloudSeed{}
+			},
+			want: []string{"spec.forProvider: FieldValueForbidden"},
+		},
+		"KubeVirtMetaData": {
+			spec: func(spec *v1alpha1.ConfigSpec) {
+				spec.WriteCloudInitToRef.Profile = v1alpha1.OutputProfileKubeVirt
+				spec.ForProvider.NoCloud = &v1alpha1.NoCloudSeed{MetaData: v1alpha1.NoCloudMetaData{InstanceID: "i-1"}}
+			},
+			want: []string{"spec.forProvider.noCloud: FieldValueForbidden"},
+		},
+		"KubeVirtConfigDriveMetaData": {
+			spec: func(spec *v1alpha1.ConfigSpec) {
+				spec.WriteCloudInitToRef.Profile = v1alpha1.OutputProfileKubeVirt
+				spec.ForProvider.ConfigDrive = &v1alpha1.ConfigDrive{MetaData: v1alpha1.ConfigDriveMetaData{Name: "vm-1"}}
+			},
+			want: []string{"spec.forProvider.configDrive.metaData: FieldValueForbidden"},
+		},
+		"VMwareGuestInfoConfigDrive": {
+			spec: func(spec *v1alpha1.ConfigSpec) {
+				spec.WriteCloudInitToRef.Profile = v1alpha1.OutputProfileVMwareGuestInfo
+				spec.ForProvider.ConfigDrive = &v1alpha1.ConfigDrive{}
+			},
+			want: []string{"spec.forProvider.configDrive: FieldValueForbidden"},
+		},
+		"LXDConfigDrive": {
+			spec: func(spec *v1alpha1.ConfigSpec) {
+				spec.WriteCloudInitToRef.Profile = v1alpha1.OutputProfileLXD
+				spec.ForProvider.ConfigDrive = &v1alpha1.ConfigDrive{}
+			},
+			want: []string{"spec.forProvider.configDrive: FieldValueForbidden"},
+		},
+		"LXDMetaData": {
+			spec: func(spec *v1alpha1.ConfigSpec) {
+				spec.WriteCloudInitToRef.Profile = v1alpha1.OutputProfileLXD
+				spec.ForProvider.NoCloud = &v1alpha1.NoCloudSeed{MetaData: v1alpha1.NoCloudMetaData{LocalHostname: "node-1"}}
+			},
+			want: []string{"spec.forProvider.noCloud.metaData: FieldValueForbidden"},
+		},
+		"IgnitionSeed": {
+			spec: func(spec *v1alpha1.ConfigSpec) {
+				spec.WriteCloudInitToRef.Format = v1alpha1.OutputFormatIgnition
+				spec.ForProvider.NoCloud = &v1alpha1.NoCloudSeed{}
+			},
+			want: []string{"spec.forProvider: FieldValueForbidden"},
+		},
+		"IgnitionTarget": {
+			spec: func(spec *v1alpha1.ConfigSpec) {
+				spec.WriteCloudInitToRef.Format = v1alpha1.OutputFormatIgnition
+				spec.ForProvider.Target = v1alpha1.TargetCloudbaseInit
+				spec.ForProvider.Parts[0].Content = "#ps1_sysnative\nWrite-Host hello\n"
+			},
+			want: []string{
+				"spec.forProvider.target: FieldValueForbidden",
+				"spec.forProvider.parts[0].content: FieldValueInvalid",
+			},
+		},
+		"IgnitionPart": {
+			spec: func(spec *v1alpha1.ConfigSpec) {
+				spec.WriteCloudInitToRef.Format = v1alpha1.OutputFormatIgnition
+				spec.ForProvider.Parts[0].Content = "#cloud-config\nruncmd: [reboot]\n"
+			},
+			want: []string{"spec.forProvider.parts[0].content: FieldValueInvalid"},
+		},
+		"IgnitionSourceContentType": {
+			spec: func(spec *v1alpha1.ConfigSpec) {
+				spec.WriteCloudInitToRef.Format = v1alpha1.OutputFormatIgnition
+				spec.ForProvider.Parts[0] = v1alpha1.PartSpec{
+					ContentFromSource: v1alpha1.ContentFromSource{ConfigMapKeyRef: &v1alpha1.DataKeySelector{NamespacedName: v1alpha1.NamespacedName{Name: "parts"}}},
+					ContentType:       "text/x-shellscript",
+				}
+			},
+			want: []string{"spec.forProvider.parts[0].contentType: FieldValueNotSupported"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			spec := newTestConfig().Spec
+			tc.spec(&spec)
+			if diff := cmp.Diff(tc.want, errorFields(validateSpec(&spec, tc.pc))); diff != "" {
+				t.Errorf("validateSpec(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDefaultSpecIdempotent(t *testing.T) {
+	cases := map[string]struct {
+		spec func(spec *v1alpha1.ConfigSpec)
+		pc   *apisv1alpha1.ProviderConfig
+	}{
+		"RandomBoundary": {
+			spec: func(spec *v1alpha1.ConfigSpec) { spec.ForProvider.Boundary = "" },
+		},
+		"StaticBoundary": {
+			spec: func(spec *v1alpha1.ConfigSpec) { spec.ForProvider.Boundary = "" },
+			pc: &apisv1alpha1.ProviderConfig{Spec: apisv1alpha1.ProviderConfigSpec{Defaults: apisv1alpha1.ConfigDefaults{
+				BoundaryStrategy: apisv1alpha1.BoundaryStrategyStatic,
+				Boundary:         "STATIC",
+			}}},
+		},
+		"ContentHashBoundary": {
+			spec: func(spec *v1alpha1.ConfigSpec) { spec.ForProvider.Boundary = "" },
+			pc: &apisv1alpha1.ProviderConfig{Spec: apisv1alpha1.ProviderConfigSpec{Defaults: apisv1alpha1.ConfigDefaults{
+				BoundaryStrategy: apisv1alpha1.BoundaryStrategyContentHash,
+			}}},
+		},
+		"ContentTypes": {
+			spec: func(spec *v1alpha1.ConfigSpec) {
+				spec.ForProvider.Parts = []v1alpha1.PartSpec{
+					{Content: "#!/bin/sh\necho hello\n"},
+					{Content: "hello"},
+					{ContentFromSource: v1alpha1.ContentFromSource{ConfigMapKeyRef: &v1alpha1.DataKeySelector{NamespacedName: v1alpha1.NamespacedName{Name: "parts"}}}},
+				}
+			},
+		},
+		"WindowsContentTypes": {
+			spec: func(spec *v1alpha1.ConfigSpec) {
+				spec.ForProvider.Target = v1alpha1.TargetCloudbaseInit
+				spec.ForProvider.Parts = []v1alpha1.PartSpec{{Content: "#ps1_sysnative\nWrite-Host hello\n"}}
+			},
+		},
+		"Seed": {
+			spec: func(spec *v1alpha1.ConfigSpec) {
+				spec.ForProvider.NoCloud = &v1alpha1.NoCloudSeed{}
+				spec.WriteCloudInitToRef.Format = v1alpha1.OutputFormatISO9660
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			spec := newTestConfig().Spec
+			tc.spec(&spec)
+			defaultSpec(&spec, nil, tc.pc)
+			once := spec.DeepCopy()
+			defaultSpec(&spec, once, tc.pc)
+			if diff := cmp.Diff(once, &spec); diff != "" {
+				t.Errorf("defaultSpec(defaultSpec(...)): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDefaulterIdempotent(t *testing.T) {
+	_, d := newTestWebhooks(t)
+	cr := newTestConfig()
+	cr.Spec.ForProvider.Boundary = ""
+	cr.Spec.ForProvider.Parts = append(cr.Spec.ForProvider.Parts, v1alpha1.PartSpec{Content: "#!/bin/sh\necho hello\n"})
+
+	created := defaulted(t, d, newAdmissionRequest(t, cr, nil))
+	res := d.Handle(context.Background(), newAdmissionRequest(t, created, created))
+	if !res.Allowed {
+		t.Fatalf("defaulter.Handle(...): %s", res.Result.Message)
+	}
+	if len(res.Patches) != 0 {
+		t.Errorf("defaulter.Handle(...): want no patches for a defaulted Config, got %v", res.Patches)
+	}
+}
@@ -0,0 +1,43 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"github.com/pkg/errors"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+
+	ciclient "github.com/crossplane-contrib/provider-cloudinit/internal/clients/cloudinit"
+	"github.com/crossplane-contrib/provider-cloudinit/internal/cloudinit"
+)
+
+const errWindowsPartFmt = "part %d is not supported by cloudbase-init"
+
+// cloudbaseInitParts sets the content type of the parts of cl to that
+// cloudbase-init would detect, as it does not detect the type of text/plain
+// parts itself, and rejects parts it does not handle
+func cloudbaseInitParts(cl *ciclient.Client) error {
+	parts := cl.GetParts()
+	cl.Parts = nil
+	for i, p := range parts {
+		ct := cloudinit.WindowsContentTypeOf(p.ContentType(), p.Content())
+		if errs := cloudinit.ValidateWindowsPart(ct, p.Content()); len(errs) > 0 {
+			return errors.Wrapf(utilerrors.NewAggregate(errs), errWindowsPartFmt, i+1)
+		}
+		cl.AppendPart(p.Content(), p.Filename(), ct, p.MergeType())
+	}
+	return nil
+}
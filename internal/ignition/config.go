@@ -0,0 +1,110 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ignition
+
+// Version is the Ignition specification version of translated configs. Both
+// Flatcar and Fedora CoreOS read it.
+const Version = "3.3.0"
+
+// A config is an Ignition config, holding the subset of the specification
+// cloud-config translates to
+type config struct {
+	Ignition ignition `json:"ignition"`
+	Passwd   *passwd  `json:"passwd,omitempty"`
+	Storage  *storage `json:"storage,omitempty"`
+	Systemd  *systemd `json:"systemd,omitempty"`
+}
+
+type ignition struct {
+	Version string `json:"version"`
+}
+
+type passwd struct {
+	Users  []*user  `json:"users,omitempty"`
+	Groups []*group `json:"groups,omitempty"`
+}
+
+type user struct {
+	Name              string   `json:"name"`
+	PasswordHash      *string  `json:"passwordHash,omitempty"`
+	SSHAuthorizedKeys []string `json:"sshAuthorizedKeys,omitempty"`
+	UID               *int64   `json:"uid,omitempty"`
+	Gecos             string   `json:"gecos,omitempty"`
+	HomeDir           string   `json:"homeDir,omitempty"`
+	NoCreateHome      bool     `json:"noCreateHome,omitempty"`
+	PrimaryGroup      string   `json:"primaryGroup,omitempty"`
+	Groups            []string `json:"groups,omitempty"`
+	NoUserGroup       bool     `json:"noUserGroup,omitempty"`
+	System            bool     `json:"system,omitempty"`
+	Shell             string   `json:"shell,omitempty"`
+}
+
+type group struct {
+	Name         string  `json:"name"`
+	GID          *int64  `json:"gid,omitempty"`
+	PasswordHash *string `json:"passwordHash,omitempty"`
+	System       bool    `json:"system,omitempty"`
+}
+
+type storage struct {
+	Files []*file `json:"files,omitempty"`
+}
+
+type file struct {
+	Path      string      `json:"path"`
+	Overwrite *bool       `json:"overwrite,omitempty"`
+	Mode      *int64      `json:"mode,omitempty"`
+	User      *nodeUser   `json:"user,omitempty"`
+	Group     *nodeGroup  `json:"group,omitempty"`
+	Contents  *resource   `json:"contents,omitempty"`
+	Append    []*resource `json:"append,omitempty"`
+}
+
+type nodeUser struct {
+	Name string `json:"name"`
+}
+
+type nodeGroup struct {
+	Name string `json:"name"`
+}
+
+type resource struct {
+	Source      string       `json:"source"`
+	Compression string       `json:"compression,omitempty"`
+	HTTPHeaders []httpHeader `json:"httpHeaders,omitempty"`
+}
+
+type httpHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type systemd struct {
+	Units []*unit `json:"units,omitempty"`
+}
+
+type unit struct {
+	Name     string    `json:"name"`
+	Enabled  *bool     `json:"enabled,omitempty"`
+	Contents *string   `json:"contents,omitempty"`
+	Dropins  []*dropin `json:"dropins,omitempty"`
+}
+
+type dropin struct {
+	Name     string `json:"name"`
+	Contents string `json:"contents"`
+}
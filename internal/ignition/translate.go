@@ -0,0 +1,719 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ignition translates cloud-config into Ignition configs, so that
+// machines booting Flatcar Container Linux or Fedora CoreOS can be
+// provisioned from the same parts as machines running cloud-init.
+package ignition
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/crossplane-contrib/provider-cloudinit/internal/cloudinit"
+)
+
+const (
+	errPartFmt        = "cannot translate part %d to Ignition"
+	errContentTypeFmt = "%s parts cannot be translated, only %s parts"
+	errParse          = "cannot parse cloud-config"
+	errUnsupportedFmt = "cloud-config keys %s cannot be translated"
+	errMarshal        = "cannot marshal Ignition config"
+
+	errTypeFmt            = "%s: must be of type %s"
+	errRequiredFmt        = "%s: is required"
+	errUserKeysFmt        = "%s: keys %s cannot be translated"
+	errPlainPasswordFmt   = "%s: plain_text_passwd cannot be translated, use hashed_passwd"
+	errGroupKeysFmt       = "%s: only group names and members can be translated"
+	errFileKeysFmt        = "%s: keys %s cannot be translated"
+	errEncodingFmt        = "%s: unknown encoding %q"
+	errDecodeFmt          = "%s: cannot decode %s content"
+	errPermissionsFmt     = "%s: invalid permissions %q"
+	errUnitSourceFmt      = "%s: systemd units cannot be fetched from a source"
+	errUnitAppendFmt      = "%s: systemd units cannot be appended to"
+	errUnitDropinNameFmt  = "%s: systemd drop-ins must be .conf files"
+	errSourceURIFmt       = "%s: source must have a uri"
+	errSourceHeadersFmt   = "%s: source headers must be strings"
+	errUnsupportedUserFmt = "%s: user entries must be names or mappings"
+)
+
+// defaultUser is the user Flatcar and Fedora CoreOS create, which stands in
+// for the default user of the distribution in cloud-config
+const defaultUser = "core"
+
+// sudoersFile is the file cloud-init writes the sudo rules of users to
+const sudoersFile = "/etc/sudoers.d/90-cloud-init-users"
+
+// unitDir is the directory systemd units written by write_files are
+// translated from
+const unitDir = "/etc/systemd/system"
+
+// unitSuffixes are the suffixes of systemd unit files
+var unitSuffixes = map[string]bool{
+	".service": true, ".socket": true, ".timer": true, ".target": true,
+	".path": true, ".mount": true, ".automount": true, ".swap": true,
+	".slice": true, ".scope": true, ".device": true,
+}
+
+// Translate returns the Ignition config equivalent to parts, as JSON. Every
+// part must be cloud-config using only keys with an Ignition equivalent:
+// users, groups, ssh_authorized_keys, write_files, hostname and fqdn. Files
+// written under /etc/systemd/system become systemd units, enabled when they
+// have an [Install] section. Lists are concatenated across parts.
+func Translate(parts []cloudinit.PartReader) ([]byte, error) {
+	t := &translator{}
+	for i, p := range parts {
+		if err := t.part(cloudinit.EffectiveContentType(p), p.Content()); err != nil {
+			return nil, errors.Wrapf(err, errPartFmt, i+1)
+		}
+	}
+	b, err := json.Marshal(t.config())
+	return b, errors.Wrap(err, errMarshal)
+}
+
+// CheckPart returns an error when a part with the supplied content type and
+// content cannot be translated to Ignition
+func CheckPart(contentType, content string) error {
+	return (&translator{}).part(cloudinit.ContentTypeOf(contentType, content), content)
+}
+
+// A translator accumulates the Ignition equivalent of cloud-config parts
+type translator struct {
+	users       []*user
+	groups      []*group
+	memberships [][2]string
+	files       []*file
+	units       []*unit
+	sudoers     []string
+	hostname    string
+}
+
+// part translates a part handled as contentType
+func (t *translator) part(contentType, content string) error {
+	if contentType != cloudinit.ContentTypeCloudConfig {
+		return errors.Errorf(errContentTypeFmt, contentType, cloudinit.ContentTypeCloudConfig)
+	}
+	cc, err := cloudinit.ParseCloudConfig(content)
+	if err != nil {
+		return errors.Wrap(err, errParse)
+	}
+
+	var unsupported []string
+	for _, k := range sortedKeys(cc) {
+		switch k {
+		case "users", "groups", "ssh_authorized_keys", "write_files", "hostname", "fqdn":
+		default:
+			unsupported = append(unsupported, k)
+		}
+	}
+	if len(unsupported) > 0 {
+		return errors.Errorf(errUnsupportedFmt, strings.Join(unsupported, ", "))
+	}
+
+	if v, ok := cc["groups"]; ok {
+		if err := t.addGroups(v); err != nil {
+			return err
+		}
+	}
+	if v, ok := cc["users"]; ok {
+		if err := t.addUsers(v); err != nil {
+			return err
+		}
+	}
+	if v, ok := cc["ssh_authorized_keys"]; ok {
+		keys, err := stringList("ssh_authorized_keys", v, false)
+		if err != nil {
+			return err
+		}
+		u := t.user(defaultUser)
+		u.SSHAuthorizedKeys = append(u.SSHAuthorizedKeys, keys...)
+	}
+	if v, ok := cc["write_files"]; ok {
+		files, ok := v.([]interface{})
+		if !ok {
+			return errors.Errorf(errTypeFmt, "write_files", "list")
+		}
+		for i, f := range files {
+			if err := t.writeFile("write_files["+strconv.Itoa(i)+"]", f); err != nil {
+				return err
+			}
+		}
+	}
+	for _, k := range []string{"fqdn", "hostname"} {
+		if v, ok := cc[k]; ok {
+			h, ok := v.(string)
+			if !ok {
+				return errors.Errorf(errTypeFmt, k, "string")
+			}
+			t.hostname = h
+		}
+	}
+	return nil
+}
+
+// user returns the user with the supplied name, adding it when there is none
+func (t *translator) user(name string) *user {
+	for _, u := range t.users {
+		if u.Name == name {
+			return u
+		}
+	}
+	u := &user{Name: name}
+	t.users = append(t.users, u)
+	return u
+}
+
+// group returns the group with the supplied name, adding it when there is
+// none
+func (t *translator) group(name string) *group {
+	for _, g := range t.groups {
+		if g.Name == name {
+			return g
+		}
+	}
+	g := &group{Name: name}
+	t.groups = append(t.groups, g)
+	return g
+}
+
+// addGroups translates the groups key, a list of group names and mappings of
+// group names to their members
+func (t *translator) addGroups(v interface{}) error {
+	if s, ok := v.(string); ok {
+		v = []interface{}{s}
+	}
+	if m, ok := v.(map[string]interface{}); ok {
+		v = []interface{}{m}
+	}
+	groups, ok := v.([]interface{})
+	if !ok {
+		return errors.Errorf(errTypeFmt, "groups", "list")
+	}
+	for i, g := range groups {
+		field := "groups[" + strconv.Itoa(i) + "]"
+		switch g := g.(type) {
+		case string:
+			for _, name := range splitList(g) {
+				t.group(name)
+			}
+		case map[string]interface{}:
+			for _, name := range sortedKeys(g) {
+				t.group(name)
+				if g[name] == nil {
+					continue
+				}
+				members, err := stringList(field+"."+name, g[name], true)
+				if err != nil {
+					return err
+				}
+				for _, m := range members {
+					t.memberships = append(t.memberships, [2]string{m, name})
+				}
+			}
+		default:
+			return errors.Errorf(errGroupKeysFmt, field)
+		}
+	}
+	return nil
+}
+
+// addUsers translates the users key, a list of user names and mappings
+func (t *translator) addUsers(v interface{}) error {
+	if s, ok := v.(string); ok {
+		v = []interface{}{s}
+	}
+	users, ok := v.([]interface{})
+	if !ok {
+		return errors.Errorf(errTypeFmt, "users", "list")
+	}
+	for i, u := range users {
+		field := "users[" + strconv.Itoa(i) + "]"
+		switch u := u.(type) {
+		case string:
+			for _, name := range splitList(u) {
+				// the default user of Flatcar and Fedora CoreOS always exists
+				if name != "default" {
+					t.user(name)
+				}
+			}
+		case map[string]interface{}:
+			if err := t.addUser(field, u); err != nil {
+				return err
+			}
+		default:
+			return errors.Errorf(errUnsupportedUserFmt, field)
+		}
+	}
+	return nil
+}
+
+// addUser translates a user mapping. Passwords are locked unless lock_passwd
+// is false, as cloud-init locks them, so their hashes are only kept then.
+func (t *translator) addUser(field string, m map[string]interface{}) error {
+	var unsupported []string
+	for _, k := range sortedKeys(m) {
+		switch k {
+		case "name", "passwd", "hashed_passwd", "lock_passwd", "ssh_authorized_keys", "uid", "gecos",
+			"homedir", "primary_group", "groups", "no_create_home", "no_user_group", "system", "shell", "sudo":
+		case "plain_text_passwd":
+			return errors.Errorf(errPlainPasswordFmt, field)
+		default:
+			unsupported = append(unsupported, k)
+		}
+	}
+	if len(unsupported) > 0 {
+		return errors.Errorf(errUserKeysFmt, field, strings.Join(unsupported, ", "))
+	}
+
+	f := fields{path: field, m: m}
+	name := f.string("name")
+	if f.err == nil && name == "" {
+		return errors.Errorf(errRequiredFmt, field+".name")
+	}
+	u := t.user(name)
+	hash := f.string("hashed_passwd")
+	if hash == "" {
+		hash = f.string("passwd")
+	}
+	if locked := f.bool("lock_passwd"); locked != nil && !*locked && hash != "" {
+		u.PasswordHash = &hash
+	}
+	u.SSHAuthorizedKeys = append(u.SSHAuthorizedKeys, f.strings("ssh_authorized_keys", false)...)
+	if uid := f.int("uid"); uid != nil {
+		u.UID = uid
+	}
+	setString(&u.Gecos, f.string("gecos"))
+	setString(&u.HomeDir, f.string("homedir"))
+	setString(&u.PrimaryGroup, f.string("primary_group"))
+	setString(&u.Shell, f.string("shell"))
+	u.Groups = append(u.Groups, f.strings("groups", true)...)
+	u.NoCreateHome = u.NoCreateHome || isTrue(f.bool("no_create_home"))
+	u.NoUserGroup = u.NoUserGroup || isTrue(f.bool("no_user_group"))
+	u.System = u.System || isTrue(f.bool("system"))
+	if v, ok := m["sudo"]; ok && v != nil && v != false {
+		for _, rule := range f.strings("sudo", false) {
+			t.sudoers = append(t.sudoers, name+" "+rule)
+		}
+	}
+	return f.err
+}
+
+// writeFile translates an entry of write_files into a file, or into a
+// systemd unit or drop-in when it is written under /etc/systemd/system
+func (t *translator) writeFile(field string, v interface{}) error {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return errors.Errorf(errTypeFmt, field, "map")
+	}
+	var unsupported []string
+	for _, k := range sortedKeys(m) {
+		switch k {
+		case "path", "content", "source", "encoding", "owner", "permissions", "append", "defer":
+		default:
+			unsupported = append(unsupported, k)
+		}
+	}
+	if len(unsupported) > 0 {
+		return errors.Errorf(errFileKeysFmt, field, strings.Join(unsupported, ", "))
+	}
+
+	f := fields{path: field, m: m}
+	p := f.string("path")
+	content := f.string("content")
+	encoding := strings.ToLower(f.string("encoding"))
+	appending := isTrue(f.bool("append"))
+	if f.err != nil {
+		return f.err
+	}
+	if p == "" {
+		return errors.Errorf(errRequiredFmt, field+".path")
+	}
+	p = path.Clean(p)
+
+	if isUnitPath(p) {
+		return t.writeUnit(field, p, m, content, encoding, appending)
+	}
+
+	r, err := fileResource(field, m, content, encoding)
+	if err != nil {
+		return err
+	}
+	fl := t.file(p)
+	if appending {
+		fl.Append = append(fl.Append, r)
+		return nil
+	}
+	overwrite := true
+	fl.Overwrite, fl.Contents, fl.Append = &overwrite, r, nil
+	fl.User, fl.Group = nil, nil
+	if owner := f.string("owner"); owner != "" {
+		parts := strings.SplitN(owner, ":", 2)
+		if parts[0] != "" && parts[0] != "root" {
+			fl.User = &nodeUser{Name: parts[0]}
+		}
+		if len(parts) == 2 && parts[1] != "" && parts[1] != "root" {
+			fl.Group = &nodeGroup{Name: parts[1]}
+		}
+	}
+	mode, err := permissions(field, m["permissions"])
+	if err != nil {
+		return err
+	}
+	fl.Mode = &mode
+	return f.err
+}
+
+// writeUnit translates an entry of write_files into a systemd unit or
+// drop-in. Units are enabled when they have an [Install] section.
+func (t *translator) writeUnit(field, p string, m map[string]interface{}, content, encoding string, appending bool) error {
+	if _, ok := m["source"]; ok {
+		return errors.Errorf(errUnitSourceFmt, field)
+	}
+	if appending {
+		return errors.Errorf(errUnitAppendFmt, field)
+	}
+	b, err := decodeContent(field, content, encoding)
+	if err != nil {
+		return err
+	}
+	contents := string(b)
+
+	dir, name := path.Dir(p), path.Base(p)
+	if dir != unitDir {
+		if path.Ext(name) != ".conf" {
+			return errors.Errorf(errUnitDropinNameFmt, field)
+		}
+		u := t.unit(strings.TrimSuffix(path.Base(dir), ".d"))
+		for _, d := range u.Dropins {
+			if d.Name == name {
+				d.Contents = contents
+				return nil
+			}
+		}
+		u.Dropins = append(u.Dropins, &dropin{Name: name, Contents: contents})
+		return nil
+	}
+	u := t.unit(name)
+	u.Contents, u.Enabled = &contents, nil
+	for _, l := range strings.Split(contents, "\n") {
+		if strings.TrimSpace(l) == "[Install]" {
+			enabled := true
+			u.Enabled = &enabled
+			break
+		}
+	}
+	return nil
+}
+
+// isUnitPath returns true when p is a systemd unit, or a drop-in of one,
+// under /etc/systemd/system
+func isUnitPath(p string) bool {
+	dir := path.Dir(p)
+	if dir == unitDir {
+		return unitSuffixes[path.Ext(p)]
+	}
+	return path.Dir(dir) == unitDir && strings.HasSuffix(dir, ".d")
+}
+
+// file returns the file at the supplied path, adding it when there is none
+func (t *translator) file(p string) *file {
+	for _, f := range t.files {
+		if f.Path == p {
+			return f
+		}
+	}
+	f := &file{Path: p}
+	t.files = append(t.files, f)
+	return f
+}
+
+// unit returns the unit with the supplied name, adding it when there is none
+func (t *translator) unit(name string) *unit {
+	for _, u := range t.units {
+		if u.Name == name {
+			return u
+		}
+	}
+	u := &unit{Name: name}
+	t.units = append(t.units, u)
+	return u
+}
+
+// config returns the Ignition config of the translated parts
+func (t *translator) config() *config {
+	for _, m := range t.memberships {
+		u := t.user(m[0])
+		u.Groups = append(u.Groups, m[1])
+	}
+	if t.hostname != "" {
+		t.addFile("/etc/hostname", 0644, t.hostname+"\n")
+	}
+	if len(t.sudoers) > 0 {
+		t.addFile(sudoersFile, 0440, strings.Join(t.sudoers, "\n")+"\n")
+	}
+
+	c := &config{Ignition: ignition{Version: Version}}
+	if len(t.users) > 0 || len(t.groups) > 0 {
+		c.Passwd = &passwd{Users: t.users, Groups: t.groups}
+	}
+	if len(t.files) > 0 {
+		c.Storage = &storage{Files: t.files}
+	}
+	if len(t.units) > 0 {
+		c.Systemd = &systemd{Units: t.units}
+	}
+	return c
+}
+
+// addFile writes a file cloud-config has no write_files entry for
+func (t *translator) addFile(p string, mode int64, content string) {
+	overwrite := true
+	f := t.file(p)
+	f.Overwrite, f.Mode, f.Contents = &overwrite, &mode, dataResource([]byte(content), "")
+}
+
+// fileResource returns the resource a file entry of write_files is read from
+func fileResource(field string, m map[string]interface{}, content, encoding string) (*resource, error) {
+	if src, ok := m["source"]; ok {
+		return sourceResource(field+".source", src)
+	}
+	switch encoding {
+	case "gz+base64", "gzip+base64", "gz+b64", "gzip+b64":
+		// Ignition decompresses gzipped contents itself
+		b, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(content), ""))
+		if err != nil {
+			return nil, errors.Errorf(errDecodeFmt, field, encoding)
+		}
+		return dataResource(b, "gzip"), nil
+	case "gz", "gzip":
+		return dataResource([]byte(content), "gzip"), nil
+	}
+	b, err := decodeContent(field, content, encoding)
+	if err != nil {
+		return nil, err
+	}
+	return dataResource(b, ""), nil
+}
+
+// sourceResource returns the resource of the source of a write_files entry
+func sourceResource(field string, v interface{}) (*resource, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.Errorf(errTypeFmt, field, "map")
+	}
+	uri, _ := m["uri"].(string)
+	if uri == "" {
+		return nil, errors.Errorf(errSourceURIFmt, field)
+	}
+	r := &resource{Source: uri}
+	if h, ok := m["headers"]; ok {
+		headers, ok := h.(map[string]interface{})
+		if !ok {
+			return nil, errors.Errorf(errSourceHeadersFmt, field)
+		}
+		for _, k := range sortedKeys(headers) {
+			v, ok := headers[k].(string)
+			if !ok {
+				return nil, errors.Errorf(errSourceHeadersFmt, field)
+			}
+			r.HTTPHeaders = append(r.HTTPHeaders, httpHeader{Name: k, Value: v})
+		}
+	}
+	return r, nil
+}
+
+// dataResource returns a resource holding b in a data URL
+func dataResource(b []byte, compression string) *resource {
+	return &resource{Source: "data:;base64," + base64.StdEncoding.EncodeToString(b), Compression: compression}
+}
+
+// decodeContent returns the content of a write_files entry, decoded as its
+// encoding asks for
+func decodeContent(field, content, encoding string) ([]byte, error) {
+	var b []byte
+	var err error
+	switch encoding {
+	case "", "text/plain":
+		return []byte(content), nil
+	case "b64", "base64":
+		b, err = base64.StdEncoding.DecodeString(strings.Join(strings.Fields(content), ""))
+	case "gz", "gzip":
+		b, err = gunzip([]byte(content))
+	case "gz+base64", "gzip+base64", "gz+b64", "gzip+b64":
+		if b, err = base64.StdEncoding.DecodeString(strings.Join(strings.Fields(content), "")); err == nil {
+			b, err = gunzip(b)
+		}
+	default:
+		return nil, errors.Errorf(errEncodingFmt, field, encoding)
+	}
+	if err != nil {
+		return nil, errors.Errorf(errDecodeFmt, field, encoding)
+	}
+	return b, nil
+}
+
+func gunzip(b []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(r)
+}
+
+// permissions returns the mode of a write_files entry, which cloud-init
+// defaults to 0644. YAML parses unquoted octal permissions as numbers.
+func permissions(field string, v interface{}) (int64, error) {
+	switch p := v.(type) {
+	case nil:
+		return 0644, nil
+	case int64:
+		if p >= 0 && p <= 07777 {
+			return p, nil
+		}
+	case string:
+		m, err := strconv.ParseInt(strings.TrimPrefix(p, "0o"), 8, 64)
+		if err == nil && m >= 0 && m <= 07777 {
+			return m, nil
+		}
+	}
+	return 0, errors.Errorf(errPermissionsFmt, field, v)
+}
+
+// fields reads the fields of a mapping, recording the first type error
+type fields struct {
+	path string
+	m    map[string]interface{}
+	err  error
+}
+
+func (f *fields) fail(k, kind string) {
+	if f.err == nil {
+		f.err = errors.Errorf(errTypeFmt, f.path+"."+k, kind)
+	}
+}
+
+func (f *fields) string(k string) string {
+	v, ok := f.m[k]
+	if !ok || v == nil {
+		return ""
+	}
+	s, ok := v.(string)
+	if !ok {
+		f.fail(k, "string")
+	}
+	return s
+}
+
+func (f *fields) bool(k string) *bool {
+	v, ok := f.m[k]
+	if !ok || v == nil {
+		return nil
+	}
+	b, ok := v.(bool)
+	if !ok {
+		f.fail(k, "boolean")
+		return nil
+	}
+	return &b
+}
+
+func (f *fields) int(k string) *int64 {
+	v, ok := f.m[k]
+	if !ok || v == nil {
+		return nil
+	}
+	i, ok := v.(int64)
+	if !ok {
+		f.fail(k, "integer")
+		return nil
+	}
+	return &i
+}
+
+func (f *fields) strings(k string, split bool) []string {
+	v, ok := f.m[k]
+	if !ok || v == nil {
+		return nil
+	}
+	s, err := stringList(f.path+"."+k, v, split)
+	if err != nil && f.err == nil {
+		f.err = err
+	}
+	return s
+}
+
+// stringList returns a string or list of strings as a list. Strings are
+// split at commas when split is true.
+func stringList(field string, v interface{}, split bool) ([]string, error) {
+	if s, ok := v.(string); ok {
+		if split {
+			return splitList(s), nil
+		}
+		return []string{s}, nil
+	}
+	l, ok := v.([]interface{})
+	if !ok {
+		return nil, errors.Errorf(errTypeFmt, field, "list")
+	}
+	out := make([]string, 0, len(l))
+	for _, e := range l {
+		s, ok := e.(string)
+		if !ok {
+			return nil, errors.Errorf(errTypeFmt, field, "list of strings")
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// splitList splits a comma separated list
+func splitList(s string) []string {
+	var out []string
+	for _, e := range strings.Split(s, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func setString(dst *string, v string) {
+	if v != "" {
+		*dst = v
+	}
+}
+
+func isTrue(b *bool) bool {
+	return b != nil && *b
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
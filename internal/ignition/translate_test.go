@@ -0,0 +1,399 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ignition
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/crossplane-contrib/provider-cloudinit/internal/cloudinit"
+)
+
+// part is a cloud-init part whose content type is detected from its content
+type part string
+
+func (p part) Filename() string    { return "" }
+func (p part) Content() string     { return string(p) }
+func (p part) ContentType() string { return "" }
+func (p part) MergeType() string   { return "" }
+
+func parts(contents ...string) []cloudinit.PartReader {
+	out := make([]cloudinit.PartReader, 0, len(contents))
+	for _, c := range contents {
+		out = append(out, part(c))
+	}
+	return out
+}
+
+func TestTranslate(t *testing.T) {
+	cases := map[string]struct {
+		parts []string
+		want  string
+	}{
+		"Empty": {
+			parts: []string{"#cloud-config\n"},
+			want:  `{"ignition": {"version": "3.3.0"}}`,
+		},
+		"Hostname": {
+			parts: []string{"#cloud-config\nhostname: node-1\n"},
+			want: `{
+				"ignition": {"version": "3.3.0"},
+				"storage": {"files": [
+					{"path": "/etc/hostname", "overwrite": true, "mode": 420, "contents": {"source": "data:;base64,bm9kZS0xCg=="}}
+				]}
+			}`,
+		},
+		"FQDN": {
+			parts: []string{"#cloud-config\nfqdn: node-1.example.com\n"},
+			want: `{
+				"ignition": {"version": "3.3.0"},
+				"storage": {"files": [
+					{"path": "/etc/hostname", "overwrite": true, "mode": 420, "contents": {"source": "data:;base64,bm9kZS0xLmV4YW1wbGUuY29tCg=="}}
+				]}
+			}`,
+		},
+		"HostnameWithFQDN": {
+			parts: []string{"#cloud-config\nfqdn: node-1.example.com\nhostname: node-1\n"},
+			want: `{
+				"ignition": {"version": "3.3.0"},
+				"storage": {"files": [
+					{"path": "/etc/hostname", "overwrite": true, "mode": 420, "contents": {"source": "data:;base64,bm9kZS0xCg=="}}
+				]}
+			}`,
+		},
+		"SSHAuthorizedKeys": {
+			parts: []string{"#cloud-config\nssh_authorized_keys:\n- ssh-ed25519 AAAA core@example\n"},
+			want: `{
+				"ignition": {"version": "3.3.0"},
+				"passwd": {"users": [{"name": "core", "sshAuthorizedKeys": ["ssh-ed25519 AAAA core@example"]}]}
+			}`,
+		},
+		"Users": {
+			parts: []string{`#cloud-config
+users:
+- default
+- alice, bob
+- name: admin
+  uid: 1001
+  gecos: Admin
+  homedir: /home/admin
+  primary_group: wheel
+  groups: adm, docker
+  shell: /bin/bash
+  system: true
+  no_create_home: true
+  no_user_group: true
+  lock_passwd: false
+  hashed_passwd: $6$admin
+  ssh_authorized_keys: [ssh-ed25519 AAAA admin@example]
+  sudo: ALL=(ALL) NOPASSWD:ALL
+- name: carol
+  passwd: $6$carol
+`},
+			want: `{
+				"ignition": {"version": "3.3.0"},
+				"passwd": {"users": [
+					{"name": "alice"},
+					{"name": "bob"},
+					{
+						"name": "admin",
+						"passwordHash": "$6$admin",
+						"sshAuthorizedKeys": ["ssh-ed25519 AAAA admin@example"],
+						"uid": 1001,
+						"gecos": "Admin",
+						"homeDir": "/home/admin",
+						"noCreateHome": true,
+						"primaryGroup": "wheel",
+						"groups": ["adm", "docker"],
+						"noUserGroup": true,
+						"system": true,
+						"shell": "/bin/bash"
+					},
+					{"name": "carol"}
+				]},
+				"storage": {"files": [
+					{"path": "/etc/sudoers.d/90-cloud-init-users", "overwrite": true, "mode": 288, "contents": {"source": "data:;base64,YWRtaW4gQUxMPShBTEwpIE5PUEFTU1dEOkFMTAo="}}
+				]}
+			}`,
+		},
+		"Groups": {
+			parts: []string{`#cloud-config
+groups:
+- ops, dev
+- admins: [alice]
+  wheel: bob, carol
+`},
+			want: `{
+				"ignition": {"version": "3.3.0"},
+				"passwd": {
+					"users": [
+						{"name": "alice", "groups": ["admins"]},
+						{"name": "bob", "groups": ["wheel"]},
+						{"name": "carol", "groups": ["wheel"]}
+					],
+					"groups": [{"name": "ops"}, {"name": "dev"}, {"name": "admins"}, {"name": "wheel"}]
+				}
+			}`,
+		},
+		"WriteFiles": {
+			parts: []string{`#cloud-config
+write_files:
+- path: /etc/motd
+  content: hello
+- path: /etc/app/secret
+  content: aGVsbG8K
+  encoding: b64
+  owner: alice:staff
+  permissions: '0600'
+- path: /opt/bin/app
+  content: H4sIAAAAAAAA/8pIzcnJBwQAAP//IDA6NgYAAAA=
+  encoding: gzip+base64
+  owner: root:root
+  permissions: 0755
+- path: /etc/app/remote.conf
+  source:
+    uri: https://example.com/remote.conf
+    headers:
+      Authorization: Bearer token
+- path: /etc/motd
+  content: |
+    line
+  append: true
+`},
+			want: `{
+				"ignition": {"version": "3.3.0"},
+				"storage": {"files": [
+					{
+						"path": "/etc/motd",
+						"overwrite": true,
+						"mode": 420,
+						"contents": {"source": "data:;base64,aGVsbG8="},
+						"append": [{"source": "data:;base64,bGluZQo="}]
+					},
+					{
+						"path": "/etc/app/secret",
+						"overwrite": true,
+						"mode": 384,
+						"user": {"name": "alice"},
+						"group": {"name": "staff"},
+						"contents": {"source": "data:;base64,aGVsbG8K"}
+					},
+					{
+						"path": "/opt/bin/app",
+						"overwrite": true,
+						"mode": 493,
+						"contents": {"source": "data:;base64,H4sIAAAAAAAA/8pIzcnJBwQAAP//IDA6NgYAAAA=", "compression": "gzip"}
+					},
+					{
+						"path": "/etc/app/remote.conf",
+						"overwrite": true,
+						"mode": 420,
+						"contents": {
+							"source": "https://example.com/remote.conf",
+							"httpHeaders": [{"name": "Authorization", "value": "Bearer token"}]
+						}
+					}
+				]}
+			}`,
+		},
+		"Units": {
+			parts: []string{`#cloud-config
+write_files:
+- path: /etc/systemd/system/app.service
+  content: |
+    [Service]
+    ExecStart=/opt/bin/app
+    [Install]
+    WantedBy=multi-user.target
+- path: /etc/systemd/system/app.timer
+  content: |
+    [Timer]
+    OnCalendar=daily
+- path: /etc/systemd/system/docker.service.d/10-proxy.conf
+  content: |
+    [Service]
+    Environment=HTTP_PROXY=http://proxy:3128
+`},
+			want: `{
+				"ignition": {"version": "3.3.0"},
+				"systemd": {"units": [
+					{
+						"name": "app.service",
+						"enabled": true,
+						"contents": "[Service]\nExecStart=/opt/bin/app\n[Install]\nWantedBy=multi-user.target\n"
+					},
+					{
+						"name": "app.timer",
+						"contents": "[Timer]\nOnCalendar=daily\n"
+					},
+					{
+						"name": "docker.service",
+						"dropins": [{"name": "10-proxy.conf", "contents": "[Service]\nEnvironment=HTTP_PROXY=http://proxy:3128\n"}]
+					}
+				]}
+			}`,
+		},
+		"ListsAcrossParts": {
+			parts: []string{
+				"#cloud-config\nssh_authorized_keys: [ssh-ed25519 AAAA one@example]\n",
+				"#cloud-config\nssh_authorized_keys: [ssh-ed25519 BBBB two@example]\nhostname: node-1\n",
+			},
+			want: `{
+				"ignition": {"version": "3.3.0"},
+				"passwd": {"users": [{"name": "core", "sshAuthorizedKeys": ["ssh-ed25519 AAAA one@example", "ssh-ed25519 BBBB two@example"]}]},
+				"storage": {"files": [
+					{"path": "/etc/hostname", "overwrite": true, "mode": 420, "contents": {"source": "data:;base64,bm9kZS0xCg=="}}
+				]}
+			}`,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			b, err := Translate(parts(tc.parts...))
+			if err != nil {
+				t.Fatalf("Translate(...): %v", err)
+			}
+			var got, want interface{}
+			if err := json.Unmarshal(b, &got); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(tc.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("Translate(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTranslateErrors(t *testing.T) {
+	cases := map[string]struct {
+		part string
+		want string
+	}{
+		"ContentType": {
+			part: "#!/bin/sh\necho hello\n",
+			want: "text/x-shellscript parts cannot be translated, only text/cloud-config parts",
+		},
+		"UnsupportedKeys": {
+			part: "#cloud-config\nruncmd: [reboot]\npackages: [curl]\nhostname: node-1\n",
+			want: "cloud-config keys packages, runcmd cannot be translated",
+		},
+		"Hostname": {
+			part: "#cloud-config\nhostname: [node-1]\n",
+			want: "hostname: must be of type string",
+		},
+		"Users": {
+			part: "#cloud-config\nusers:\n  alice: {}\n",
+			want: "users: must be of type list",
+		},
+		"UserEntry": {
+			part: "#cloud-config\nusers: [1001]\n",
+			want: "users[0]: user entries must be names or mappings",
+		},
+		"UserName": {
+			part: "#cloud-config\nusers:\n- shell: /bin/bash\n",
+			want: "users[0].name: is required",
+		},
+		"UserKeys": {
+			part: "#cloud-config\nusers:\n- name: alice\n  selinux_user: staff_u\n  expiredate: '2030-01-01'\n",
+			want: "users[0]: keys expiredate, selinux_user cannot be translated",
+		},
+		"PlainTextPassword": {
+			part: "#cloud-config\nusers:\n- name: alice\n  plain_text_passwd: secret\n",
+			want: "users[0]: plain_text_passwd cannot be translated, use hashed_passwd",
+		},
+		"UserFieldType": {
+			part: "#cloud-config\nusers:\n- name: alice\n  uid: '1001'\n",
+			want: "users[0].uid: must be of type integer",
+		},
+		"Groups": {
+			part: "#cloud-config\ngroups: [[ops]]\n",
+			want: "groups[0]: only group names and members can be translated",
+		},
+		"GroupMembers": {
+			part: "#cloud-config\ngroups:\n- ops: [1001]\n",
+			want: "groups[0].ops: must be of type list of strings",
+		},
+		"SSHAuthorizedKeys": {
+			part: "#cloud-config\nssh_authorized_keys: {core: key}\n",
+			want: "ssh_authorized_keys: must be of type list",
+		},
+		"WriteFiles": {
+			part: "#cloud-config\nwrite_files:\n  path: /etc/motd\n",
+			want: "write_files: must be of type list",
+		},
+		"FilePath": {
+			part: "#cloud-config\nwrite_files:\n- content: hello\n",
+			want: "write_files[0].path: is required",
+		},
+		"FileKeys": {
+			part: "#cloud-config\nwrite_files:\n- path: /etc/motd\n  template: jinja\n",
+			want: "write_files[0]: keys template cannot be translated",
+		},
+		"Encoding": {
+			part: "#cloud-config\nwrite_files:\n- path: /etc/motd\n  content: hello\n  encoding: rot13\n",
+			want: `write_files[0]: unknown encoding "rot13"`,
+		},
+		"Decode": {
+			part: "#cloud-config\nwrite_files:\n- path: /etc/motd\n  content: not base64!\n  encoding: b64\n",
+			want: "write_files[0]: cannot decode b64 content",
+		},
+		"Permissions": {
+			part: "#cloud-config\nwrite_files:\n- path: /etc/motd\n  permissions: rw-r--r--\n",
+			want: `write_files[0]: invalid permissions "rw-r--r--"`,
+		},
+		"SourceURI": {
+			part: "#cloud-config\nwrite_files:\n- path: /etc/motd\n  source: {headers: {}}\n",
+			want: "write_files[0].source: source must have a uri",
+		},
+		"SourceHeaders": {
+			part: "#cloud-config\nwrite_files:\n- path: /etc/motd\n  source: {uri: 'https://example.com/motd', headers: {X-Retries: 3}}\n",
+			want: "write_files[0].source: source headers must be strings",
+		},
+		"UnitSource": {
+			part: "#cloud-config\nwrite_files:\n- path: /etc/systemd/system/app.service\n  source: {uri: 'https://example.com/app.service'}\n",
+			want: "write_files[0]: systemd units cannot be fetched from a source",
+		},
+		"UnitAppend": {
+			part: "#cloud-config\nwrite_files:\n- path: /etc/systemd/system/app.service\n  content: '[Service]'\n  append: true\n",
+			want: "write_files[0]: systemd units cannot be appended to",
+		},
+		"DropinName": {
+			part: "#cloud-config\nwrite_files:\n- path: /etc/systemd/system/app.service.d/override\n  content: '[Service]'\n",
+			want: "write_files[0]: systemd drop-ins must be .conf files",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := Translate(parts("#cloud-config\n", tc.part))
+			got := ""
+			if err != nil {
+				got = err.Error()
+			}
+			if diff := cmp.Diff("cannot translate part 2 to Ignition: "+tc.want, got); diff != "" {
+				t.Errorf("Translate(...): -want, +got:\n%s", diff)
+			}
+			if err := CheckPart("", tc.part); err == nil {
+				t.Errorf("CheckPart(...): want error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,479 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package iso9660 writes ISO9660 images with Joliet extensions, as read by
+// cloud-init from NoCloud and config drive volumes. Images are reproducible:
+// the same files and volume identifier always produce the same bytes.
+package iso9660
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	sectorSize = 2048
+
+	// the first sectors of an image are reserved for the system area
+	systemAreaSectors = 16
+
+	errEmptyPath     = "file paths must not be empty"
+	errFileIsDirFmt  = "%q is both a file and a directory"
+	errNameLengthFmt = "name %q is too long for a Joliet image"
+
+	maxJolietName = 64
+)
+
+// recordingDate is the date of every directory record. Images must not
+// depend on when they were written.
+var recordingDate = [7]byte{70, 1, 1, 0, 0, 0, 0}
+
+// A node is a file or directory of an image
+type node struct {
+	name     string
+	data     []byte
+	children map[string]*node
+	extent   uint32
+}
+
+func (n *node) isDir() bool {
+	return n.children != nil
+}
+
+// A hierarchy is one of the directory hierarchies of an image. The primary
+// hierarchy uses ISO9660 level 1 names, and the Joliet hierarchy the
+// original names in UCS-2.
+type hierarchy struct {
+	// dirs are the directories in path table order
+	dirs    []*node
+	number  map[*node]int
+	parent  map[*node]*node
+	entries map[*node][]entry
+	extent  map[*node]uint32
+	size    map[*node]uint32
+
+	pathTableSize          int
+	pathTableL, pathTableM uint32
+}
+
+// An entry is a child of a directory with its identifier in a hierarchy
+type entry struct {
+	id   []byte
+	node *node
+}
+
+// Write writes an image labelled with volumeID holding the supplied files to
+// w. Files are keyed by their slash separated path.
+func Write(w io.Writer, volumeID string, files map[string][]byte) error {
+	root, err := tree(files)
+	if err != nil {
+		return err
+	}
+	primary := newHierarchy(root, primaryIdentifiers)
+	joliet := newHierarchy(root, jolietIdentifiers)
+	for _, d := range joliet.dirs {
+		for _, e := range joliet.entries[d] {
+			if len(e.id) > 2*maxJolietName {
+				return errors.Errorf(errNameLengthFmt, e.node.name)
+			}
+		}
+	}
+
+	// volume descriptors, then path tables, directories and file data
+	sector := uint32(systemAreaSectors + 3)
+	for _, h := range []*hierarchy{primary, joliet} {
+		h.pathTableL = sector
+		sector += sectors(h.pathTableSize)
+		h.pathTableM = sector
+		sector += sectors(h.pathTableSize)
+	}
+	for _, h := range []*hierarchy{primary, joliet} {
+		for _, d := range h.dirs {
+			h.extent[d] = sector
+			sector += h.size[d] / sectorSize
+		}
+	}
+	data := sortedFiles(root)
+	for _, f := range data {
+		f.extent = sector
+		sector += sectors(len(f.data))
+	}
+	total := sector
+
+	b := &bytes.Buffer{}
+	b.Write(make([]byte, systemAreaSectors*sectorSize))
+	b.Write(volumeDescriptor(1, volumeID, total, primary, root, false))
+	b.Write(volumeDescriptor(2, volumeID, total, joliet, root, true))
+	b.Write(terminator())
+	for _, h := range []*hierarchy{primary, joliet} {
+		writePadded(b, h.pathTableBytes(binary.LittleEndian))
+		writePadded(b, h.pathTableBytes(binary.BigEndian))
+	}
+	for _, h := range []*hierarchy{primary, joliet} {
+		for _, d := range h.dirs {
+			b.Write(h.directory(d))
+		}
+	}
+	for _, f := range data {
+		writePadded(b, f.data)
+	}
+	_, err = w.Write(b.Bytes())
+	return err
+}
+
+// tree returns the root directory of the supplied files
+func tree(files map[string][]byte) (*node, error) {
+	root := &node{children: map[string]*node{}}
+	for p, data := range files {
+		parts := strings.Split(strings.Trim(p, "/"), "/")
+		if parts[0] == "" {
+			return nil, errors.New(errEmptyPath)
+		}
+		d := root
+		for _, name := range parts[:len(parts)-1] {
+			c, ok := d.children[name]
+			if !ok {
+				c = &node{name: name, children: map[string]*node{}}
+				d.children[name] = c
+			}
+			if !c.isDir() {
+				return nil, errors.Errorf(errFileIsDirFmt, name)
+			}
+			d = c
+		}
+		name := parts[len(parts)-1]
+		if c, ok := d.children[name]; ok && c.isDir() {
+			return nil, errors.Errorf(errFileIsDirFmt, name)
+		}
+		d.children[name] = &node{name: name, data: data}
+	}
+	return root, nil
+}
+
+// sortedFiles returns the files of the tree ordered by path
+func sortedFiles(root *node) []*node {
+	out := make([]*node, 0)
+	var walk func(d *node)
+	walk = func(d *node) {
+		for _, name := range sortedNames(d) {
+			c := d.children[name]
+			if c.isDir() {
+				walk(c)
+				continue
+			}
+			out = append(out, c)
+		}
+	}
+	walk(root)
+	return out
+}
+
+func sortedNames(d *node) []string {
+	names := make([]string, 0, len(d.children))
+	for n := range d.children {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newHierarchy returns the hierarchy of the tree under root, identifying
+// the children of each directory with ids
+func newHierarchy(root *node, ids func(d *node) []entry) *hierarchy {
+	h := &hierarchy{
+		number:  map[*node]int{},
+		parent:  map[*node]*node{root: root},
+		entries: map[*node][]entry{},
+		extent:  map[*node]uint32{},
+		size:    map[*node]uint32{},
+	}
+	// path tables list directories breadth first, each level ordered by
+	// parent and identifier
+	queue := []*node{root}
+	for len(queue) > 0 {
+		d := queue[0]
+		queue = queue[1:]
+		h.dirs = append(h.dirs, d)
+		h.number[d] = len(h.dirs)
+		h.entries[d] = ids(d)
+		h.size[d] = directorySize(h.entries[d])
+		for _, e := range h.entries[d] {
+			if e.node.isDir() {
+				h.parent[e.node] = d
+				queue = append(queue, e.node)
+			}
+		}
+	}
+	for _, d := range h.dirs {
+		h.pathTableSize += pathTableEntrySize(h.id(d))
+	}
+	return h
+}
+
+// id returns the identifier of a directory in the hierarchy
+func (h *hierarchy) id(d *node) []byte {
+	p := h.parent[d]
+	if p == d {
+		return []byte{0}
+	}
+	for _, e := range h.entries[p] {
+		if e.node == d {
+			return e.id
+		}
+	}
+	return nil
+}
+
+func (h *hierarchy) pathTableBytes(order binary.ByteOrder) []byte {
+	b := &bytes.Buffer{}
+	for _, d := range h.dirs {
+		id := h.id(d)
+		b.WriteByte(byte(len(id)))
+		b.WriteByte(0)
+		_ = binary.Write(b, order, h.extent[d])
+		_ = binary.Write(b, order, uint16(h.number[h.parent[d]]))
+		b.Write(id)
+		if len(id)%2 == 1 {
+			b.WriteByte(0)
+		}
+	}
+	return b.Bytes()
+}
+
+// directory returns the sectors of a directory's records
+func (h *hierarchy) directory(d *node) []byte {
+	records := [][]byte{
+		record([]byte{0}, h.extent[d], h.size[d], true),
+		record([]byte{1}, h.extent[h.parent[d]], h.size[h.parent[d]], true),
+	}
+	for _, e := range h.entries[d] {
+		if e.node.isDir() {
+			records = append(records, record(e.id, h.extent[e.node], h.size[e.node], true))
+			continue
+		}
+		records = append(records, record(e.id, e.node.extent, uint32(len(e.node.data)), false))
+	}
+
+	b := make([]byte, 0, h.size[d])
+	for _, r := range records {
+		// records must not span sectors
+		if used := len(b) % sectorSize; used+len(r) > sectorSize {
+			b = append(b, make([]byte, sectorSize-used)...)
+		}
+		b = append(b, r...)
+	}
+	return append(b, make([]byte, int(h.size[d])-len(b))...)
+}
+
+// directorySize returns the size of a directory holding the supplied
+// entries, in whole sectors
+func directorySize(entries []entry) uint32 {
+	size := 2 * recordSize([]byte{0})
+	for _, e := range entries {
+		r := recordSize(e.id)
+		if used := size % sectorSize; used+r > sectorSize {
+			size += sectorSize - used
+		}
+		size += r
+	}
+	return sectors(size) * sectorSize
+}
+
+func recordSize(id []byte) int {
+	return 33 + len(id) + (len(id)+1)%2
+}
+
+func pathTableEntrySize(id []byte) int {
+	return 8 + len(id) + len(id)%2
+}
+
+// record returns a directory record
+func record(id []byte, extent, size uint32, dir bool) []byte {
+	r := make([]byte, recordSize(id))
+	r[0] = byte(len(r))
+	putBoth32(r[2:], extent)
+	putBoth32(r[10:], size)
+	copy(r[18:25], recordingDate[:])
+	if dir {
+		r[25] = 0x02
+	}
+	putBoth16(r[28:], 1)
+	r[32] = byte(len(id))
+	copy(r[33:], id)
+	return r
+}
+
+// volumeDescriptor returns a primary (type 1) or Joliet supplementary (type
+// 2) volume descriptor
+func volumeDescriptor(typ byte, volumeID string, total uint32, h *hierarchy, root *node, joliet bool) []byte {
+	text := func(s string, n int) []byte {
+		if joliet {
+			return ucs2(s, n)
+		}
+		return padded(strings.ToUpper(s), n)
+	}
+
+	d := make([]byte, sectorSize)
+	d[0] = typ
+	copy(d[1:6], "CD001")
+	d[6] = 1
+	copy(d[8:40], text("", 32))
+	copy(d[40:72], text(volumeID, 32))
+	putBoth32(d[80:], total)
+	if joliet {
+		// UCS-2 level 3
+		copy(d[88:91], "%/E")
+	}
+	putBoth16(d[120:], 1)
+	putBoth16(d[124:], 1)
+	putBoth16(d[128:], sectorSize)
+	putBoth32(d[132:], uint32(h.pathTableSize))
+	binary.LittleEndian.PutUint32(d[140:], h.pathTableL)
+	binary.BigEndian.PutUint32(d[148:], h.pathTableM)
+	copy(d[156:190], record([]byte{0}, h.extent[root], h.size[root], true))
+	for _, f := range [][2]int{{190, 128}, {318, 128}, {446, 128}, {574, 128}, {702, 37}, {739, 37}, {776, 37}} {
+		copy(d[f[0]:f[0]+f[1]], text("", f[1]))
+	}
+	// creation, modification, expiration and effective dates are unset
+	for off := 813; off < 881; off += 17 {
+		copy(d[off:off+16], strings.Repeat("0", 16))
+	}
+	d[881] = 1
+	return d
+}
+
+func terminator() []byte {
+	d := make([]byte, sectorSize)
+	d[0] = 255
+	copy(d[1:6], "CD001")
+	d[6] = 1
+	return d
+}
+
+// primaryIdentifiers returns the children of d with ISO9660 level 1
+// identifiers: upper case, at most eight characters and a three character
+// extension. Names made equal by shortening are made unique.
+func primaryIdentifiers(d *node) []entry {
+	entries := make([]entry, 0, len(d.children))
+	used := map[string]bool{}
+	for _, name := range sortedNames(d) {
+		c := d.children[name]
+		base, ext := name, ""
+		if i := strings.LastIndex(name, "."); i > 0 && !c.isDir() {
+			base, ext = name[:i], name[i+1:]
+		}
+		base, ext = dChars(base, 8), dChars(ext, 3)
+		id := func(b string) string {
+			if c.isDir() {
+				return b
+			}
+			return b + "." + ext + ";1"
+		}
+		for i := 1; used[id(base)]; i++ {
+			suffix := "~" + strconv.Itoa(i)
+			b := base
+			if len(b)+len(suffix) > 8 {
+				b = b[:8-len(suffix)]
+			}
+			base = b + suffix
+		}
+		used[id(base)] = true
+		entries = append(entries, entry{id: []byte(id(base)), node: c})
+	}
+	sortEntries(entries)
+	return entries
+}
+
+// jolietIdentifiers returns the children of d with their names in UCS-2
+func jolietIdentifiers(d *node) []entry {
+	entries := make([]entry, 0, len(d.children))
+	for _, name := range sortedNames(d) {
+		entries = append(entries, entry{id: ucs2(name, 0), node: d.children[name]})
+	}
+	sortEntries(entries)
+	return entries
+}
+
+func sortEntries(entries []entry) {
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].id, entries[j].id) < 0
+	})
+}
+
+// dChars returns s in upper case d-characters, at most n long
+func dChars(s string, n int) string {
+	b := make([]byte, 0, n)
+	for _, r := range strings.ToUpper(s) {
+		if len(b) == n {
+			break
+		}
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			b = append(b, byte(r))
+			continue
+		}
+		b = append(b, '_')
+	}
+	return string(b)
+}
+
+// ucs2 returns s in big endian UCS-2, space padded to n bytes when n is not
+// zero
+func ucs2(s string, n int) []byte {
+	u := utf16.Encode([]rune(s))
+	b := make([]byte, 0, 2*len(u))
+	for _, c := range u {
+		b = append(b, byte(c>>8), byte(c))
+	}
+	if n == 0 {
+		return b
+	}
+	for len(b) < n {
+		b = append(b, 0, ' ')
+	}
+	return b[:n]
+}
+
+func padded(s string, n int) []byte {
+	return []byte((s + strings.Repeat(" ", n))[:n])
+}
+
+func putBoth16(b []byte, v uint16) {
+	binary.LittleEndian.PutUint16(b, v)
+	binary.BigEndian.PutUint16(b[2:], v)
+}
+
+func putBoth32(b []byte, v uint32) {
+	binary.LittleEndian.PutUint32(b, v)
+	binary.BigEndian.PutUint32(b[4:], v)
+}
+
+func sectors(n int) uint32 {
+	return uint32((n + sectorSize - 1) / sectorSize)
+}
+
+func writePadded(b *bytes.Buffer, data []byte) {
+	b.Write(data)
+	if r := len(data) % sectorSize; r != 0 {
+		b.Write(make([]byte, sectorSize-r))
+	}
+}
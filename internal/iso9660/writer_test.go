@@ -0,0 +1,215 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iso9660
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// seed is a config drive, with names that ISO9660 level 1 cannot hold
+var seed = map[string]string{
+	"openstack/latest/meta_data.json":    `{"uuid": "0b6e3a44"}`,
+	"openstack/latest/network_data.json": `{"links": []}`,
+	"openstack/latest/user_data":         "#cloud-config\n",
+	"openstack/content/0000":             strings.Repeat("x", 3*sectorSize+1),
+	"user-data":                          "#cloud-config\n",
+	"network-config":                     "version: 2\n",
+}
+
+// image reads back a written image
+type image []byte
+
+func (im image) sector(n uint32) []byte {
+	return im[n*sectorSize : (n+1)*sectorSize]
+}
+
+// descriptor returns the first volume descriptor of the supplied type
+func (im image) descriptor(t *testing.T, typ byte) []byte {
+	t.Helper()
+	for n := uint32(systemAreaSectors); int(n+1)*sectorSize <= len(im); n++ {
+		d := im.sector(n)
+		if string(d[1:6]) != "CD001" {
+			t.Fatalf("sector %d: want a volume descriptor", n)
+		}
+		if d[0] == typ {
+			return d
+		}
+		if d[0] == 255 {
+			break
+		}
+	}
+	t.Fatalf("want a volume descriptor of type %d", typ)
+	return nil
+}
+
+// files returns the files of the hierarchy of the volume descriptor d, keyed
+// by their path, with their names decoded from UCS-2 when joliet is true
+func (im image) files(d []byte, joliet bool) map[string]string {
+	files := map[string]string{}
+	var walk func(prefix string, extent, size uint32)
+	walk = func(prefix string, extent, size uint32) {
+		dir := im[extent*sectorSize : extent*sectorSize+size]
+		for off, n := 0, 0; off < len(dir); {
+			r := dir[off:]
+			if r[0] == 0 {
+				// records do not cross sectors
+				off = (off/sectorSize + 1) * sectorSize
+				continue
+			}
+			off += int(r[0])
+			if n++; n <= 2 {
+				// the directory itself and its parent
+				continue
+			}
+			ext, sz := binary.LittleEndian.Uint32(r[2:]), binary.LittleEndian.Uint32(r[10:])
+			name := string(r[33 : 33+r[32]])
+			if joliet {
+				name = fromUCS2(r[33 : 33+r[32]])
+			}
+			if r[25]&0x02 != 0 {
+				walk(prefix+name+"/", ext, sz)
+				continue
+			}
+			files[prefix+name] = string(im[ext*sectorSize : ext*sectorSize+sz])
+		}
+	}
+	root := d[156:]
+	walk("", binary.LittleEndian.Uint32(root[2:]), binary.LittleEndian.Uint32(root[10:]))
+	return files
+}
+
+func fromUCS2(b []byte) string {
+	u := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		u = append(u, uint16(b[i])<<8|uint16(b[i+1]))
+	}
+	return string(utf16.Decode(u))
+}
+
+func write(t *testing.T, volumeID string, files map[string][]byte) image {
+	t.Helper()
+	b := &bytes.Buffer{}
+	if err := Write(b, volumeID, files); err != nil {
+		t.Fatalf("Write(...): %v", err)
+	}
+	if b.Len()%sectorSize != 0 {
+		t.Fatalf("Write(...): want whole sectors, got %d bytes", b.Len())
+	}
+	return image(b.Bytes())
+}
+
+func TestWriteReproducible(t *testing.T) {
+	names := make([]string, 0, len(seed))
+	for name := range seed {
+		names = append(names, name)
+	}
+	forward := map[string][]byte{}
+	for _, name := range names {
+		forward[name] = []byte(seed[name])
+	}
+	backward := map[string][]byte{}
+	for i := len(names) - 1; i >= 0; i-- {
+		backward[names[i]] = []byte(seed[names[i]])
+	}
+
+	first := write(t, "config-2", forward)
+	for name, files := range map[string]map[string][]byte{"Again": forward, "OtherInsertionOrder": backward} {
+		t.Run(name, func(t *testing.T) {
+			if !bytes.Equal(first, write(t, "config-2", files)) {
+				t.Errorf("Write(...): want the same bytes for the same files")
+			}
+		})
+	}
+}
+
+func TestWriteJoliet(t *testing.T) {
+	im := write(t, "config-2", stringFiles(seed))
+	d := im.descriptor(t, 2)
+	if diff := cmp.Diff("%/E", string(d[88:91])); diff != "" {
+		t.Errorf("Write(...): Joliet escape sequence: -want, +got:\n%s", diff)
+	}
+	if diff := cmp.Diff(seed, im.files(d, true)); diff != "" {
+		t.Errorf("Write(...): Joliet files: -want, +got:\n%s", diff)
+	}
+}
+
+func TestWritePrimary(t *testing.T) {
+	im := write(t, "cidata", stringFiles(map[string]string{
+		"user-data":       "one",
+		"user-data-extra": "two",
+		"meta-data":       "three",
+		"dir.d/file.conf": "four",
+	}))
+	want := map[string]string{
+		"USER_DAT.;1":      "one",
+		"USER_D~1.;1":      "two",
+		"META_DAT.;1":      "three",
+		"DIR_D/FILE.CON;1": "four",
+	}
+	if diff := cmp.Diff(want, im.files(im.descriptor(t, 1), false)); diff != "" {
+		t.Errorf("Write(...): primary files: -want, +got:\n%s", diff)
+	}
+}
+
+func TestWriteVolumeLabel(t *testing.T) {
+	cases := map[string]string{
+		"NoCloud":     "cidata",
+		"ConfigDrive": "config-2",
+	}
+	for name, label := range cases {
+		t.Run(name, func(t *testing.T) {
+			im := write(t, label, stringFiles(map[string]string{"user-data": ""}))
+			primary := strings.TrimRight(string(im.descriptor(t, 1)[40:72]), " ")
+			if diff := cmp.Diff(strings.ToUpper(label), primary); diff != "" {
+				t.Errorf("Write(...): primary volume label: -want, +got:\n%s", diff)
+			}
+			joliet := strings.TrimRight(fromUCS2(im.descriptor(t, 2)[40:72]), " ")
+			if diff := cmp.Diff(label, joliet); diff != "" {
+				t.Errorf("Write(...): Joliet volume label: -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWriteErrors(t *testing.T) {
+	cases := map[string]map[string]string{
+		"EmptyPath":      {"": "data"},
+		"FileIsDir":      {"openstack": "data", "openstack/latest/user_data": "data"},
+		"JolietNameLong": {strings.Repeat("n", maxJolietName+1): "data"},
+	}
+	for name, files := range cases {
+		t.Run(name, func(t *testing.T) {
+			if err := Write(&bytes.Buffer{}, "cidata", stringFiles(files)); err == nil {
+				t.Errorf("Write(...): want error, got nil")
+			}
+		})
+	}
+}
+
+func stringFiles(files map[string]string) map[string][]byte {
+	out := make(map[string][]byte, len(files))
+	for name, content := range files {
+		out[name] = []byte(content)
+	}
+	return out
+}
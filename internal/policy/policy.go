@@ -0,0 +1,247 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package policy evaluates ProviderConfig policy rules, expressed in CEL,
+// against the parts of a rendered cloud-init document.
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+
+	apisv1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/v1alpha1"
+	"github.com/crossplane-contrib/provider-cloudinit/internal/cloudinit"
+)
+
+const (
+	errEnv            = "cannot create CEL environment"
+	errCompileFmt     = "cannot compile policy rule %q"
+	errEvalFmt        = "cannot evaluate policy rule %q"
+	errNotBoolFmt     = "policy rule %q does not evaluate to a bool"
+	errCloudConfigFmt = "cannot parse cloud-config of part %d"
+)
+
+// A Violation is returned when a rendered document does not satisfy a rule
+type Violation struct {
+	Rule    string
+	Message string
+}
+
+// Error returns the violation as a string
+func (v *Violation) Error() string {
+	return fmt.Sprintf("policy rule %q violated: %s", v.Rule, v.Message)
+}
+
+// IsViolation returns the Violation that caused err, if any
+func IsViolation(err error) (*Violation, bool) {
+	v, ok := errors.Cause(err).(*Violation)
+	return v, ok
+}
+
+// Check evaluates each rule in order against the supplied parts. It returns
+// a *Violation for the first rule that is not satisfied.
+func Check(rules []apisv1alpha1.PolicyRule, parts []cloudinit.PartReader) error {
+	if len(rules) == 0 {
+		return nil
+	}
+	env, err := cel.NewEnv(cel.Declarations(
+		decls.NewVar("parts", decls.NewListType(decls.NewMapType(decls.String, decls.Dyn))),
+	))
+	if err != nil {
+		return errors.Wrap(err, errEnv)
+	}
+	vars, err := Variables(parts)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range rules {
+		ast, iss := env.Compile(r.Expression)
+		if iss != nil && iss.Err() != nil {
+			return errors.Wrapf(iss.Err(), errCompileFmt, r.Name)
+		}
+		prg, err := env.Program(ast)
+		if err != nil {
+			return errors.Wrapf(err, errCompileFmt, r.Name)
+		}
+		out, _, err := prg.Eval(vars)
+		if err != nil {
+			return errors.Wrapf(err, errEvalFmt, r.Name)
+		}
+		ok, isBool := out.Value().(bool)
+		if !isBool {
+			return errors.Errorf(errNotBoolFmt, r.Name)
+		}
+		if !ok {
+			msg := r.Message
+			if msg == "" {
+				msg = r.Expression
+			}
+			return &Violation{Rule: r.Name, Message: msg}
+		}
+	}
+	return nil
+}
+
+// Variables returns the CEL activation rules are evaluated with
+func Variables(parts []cloudinit.PartReader) (map[string]interface{}, error) {
+	list := make([]interface{}, 0, len(parts))
+	for i, p := range parts {
+		ct := cloudinit.EffectiveContentType(p)
+		m := map[string]interface{}{
+			"contentType":  ct,
+			"filename":     p.Filename(),
+			"mergeType":    p.MergeType(),
+			"content":      p.Content(),
+			"cloudConfig":  map[string]interface{}{},
+			"includes":     []interface{}{},
+			"includeHosts": []interface{}{},
+		}
+		c := &partContent{cloudConfig: map[string]interface{}{}}
+		if err := c.add(ct, p.Content()); err != nil {
+			return nil, errors.Wrapf(err, errCloudConfigFmt, i)
+		}
+		includes := make([]interface{}, 0, len(c.includes))
+		hosts := make([]interface{}, 0, len(c.includes))
+		for _, u := range c.includes {
+			includes = append(includes, u)
+			host := ""
+			if pu, err := url.Parse(u); err == nil {
+				host = pu.Hostname()
+			}
+			hosts = append(hosts, host)
+		}
+		m["cloudConfig"] = c.cloudConfig
+		m["includes"] = includes
+		m["includeHosts"] = hosts
+		list = append(list, m)
+	}
+	return map[string]interface{}{"parts": list}, nil
+}
+
+// partContent is the cloud-config and the include URLs of a part, including
+// those of the patches of a cloud-config-jsonp part and of the entries of a
+// cloud-config-archive part
+type partContent struct {
+	cloudConfig map[string]interface{}
+	includes    []string
+}
+
+func (c *partContent) add(contentType, content string) error {
+	switch contentType {
+	case cloudinit.ContentTypeCloudConfig:
+		cc, err := cloudinit.ParseCloudConfig(content)
+		if err != nil {
+			return err
+		}
+		c.merge(cc)
+	case cloudinit.ContentTypeCloudConfigJSONP:
+		return c.addPatches(content)
+	case cloudinit.ContentTypeCloudConfigArchive:
+		entries := []struct {
+			Type    string `json:"type"`
+			Content string `json:"content"`
+		}{}
+		body := strings.TrimPrefix(content, "#cloud-config-archive")
+		if err := yaml.Unmarshal([]byte(body), &entries); err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if err := c.add(cloudinit.ContentTypeOf(e.Type, e.Content), e.Content); err != nil {
+				return err
+			}
+		}
+	case cloudinit.ContentTypeIncludeURL, cloudinit.ContentTypeIncludeOnceURL:
+		c.includes = append(c.includes, cloudinit.IncludeURLs(content)...)
+	}
+	return nil
+}
+
+// addPatches adds the values a cloud-config-jsonp part patches in as the
+// top-level key of their path. Values patched into a key, rather than
+// replacing it, are collected in a list.
+func (c *partContent) addPatches(content string) error {
+	ops := []struct {
+		Op    string      `json:"op"`
+		Path  string      `json:"path"`
+		Value interface{} `json:"value"`
+	}{}
+	body := strings.TrimPrefix(content, "#cloud-config-jsonp")
+	if err := json.Unmarshal([]byte(body), &ops); err != nil {
+		return err
+	}
+	patched := map[string]interface{}{}
+	for _, op := range ops {
+		if op.Op == "remove" || op.Op == "test" {
+			continue
+		}
+		path := strings.SplitN(strings.TrimPrefix(op.Path, "/"), "/", 2)
+		if len(path) == 1 {
+			patched[path[0]] = mergeValues(patched[path[0]], op.Value)
+			continue
+		}
+		patched[path[0]] = mergeValues(patched[path[0]], []interface{}{op.Value})
+	}
+	// round trip through ParseCloudConfig, so that numbers are typed as
+	// they are in cloud-config parts
+	j, err := json.Marshal(patched)
+	if err != nil {
+		return err
+	}
+	cc, err := cloudinit.ParseCloudConfig(string(j))
+	if err != nil {
+		return err
+	}
+	c.merge(cc)
+	return nil
+}
+
+// merge merges cc into the cloud-config of the part. Lists are appended and
+// mappings merged, so that rules see every value set in the part, whatever
+// the merge types cloud-init applies.
+func (c *partContent) merge(cc map[string]interface{}) {
+	for k, v := range cc {
+		c.cloudConfig[k] = mergeValues(c.cloudConfig[k], v)
+	}
+}
+
+func mergeValues(a, b interface{}) interface{} {
+	switch at := a.(type) {
+	case []interface{}:
+		if bt, ok := b.([]interface{}); ok {
+			return append(append([]interface{}{}, at...), bt...)
+		}
+	case map[string]interface{}:
+		if bt, ok := b.(map[string]interface{}); ok {
+			m := make(map[string]interface{}, len(at)+len(bt))
+			for k, v := range at {
+				m[k] = v
+			}
+			for k, v := range bt {
+				m[k] = mergeValues(m[k], v)
+			}
+			return m
+		}
+	}
+	return b
+}
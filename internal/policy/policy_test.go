@@ -0,0 +1,172 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	apisv1alpha1 "github.com/crossplane-contrib/provider-cloudinit/apis/v1alpha1"
+	"github.com/crossplane-contrib/provider-cloudinit/internal/cloudinit"
+)
+
+// part is a cloud-init part whose content type is detected from its content
+type part string
+
+func (p part) Filename() string    { return "" }
+func (p part) Content() string     { return string(p) }
+func (p part) ContentType() string { return "" }
+func (p part) MergeType() string   { return "" }
+
+func TestCheck(t *testing.T) {
+	rules := []apisv1alpha1.PolicyRule{
+		{Name: "no-runcmd", Expression: "parts.all(p, !has(p.cloudConfig.runcmd))"},
+		{Name: "trusted-includes", Expression: `parts.all(p, p.includeHosts.all(h, h == "trusted.example.com"))`},
+	}
+	cases := map[string]struct {
+		parts []cloudinit.PartReader
+		want  string
+	}{
+		"Allowed": {
+			parts: []cloudinit.PartReader{
+				part("#cloud-config\nhostname: node-1\n"),
+				part("#include\nhttps://trusted.example.com/user-data\n"),
+			},
+		},
+		"CloudConfig": {
+			parts: []cloudinit.PartReader{part("#cloud-config\nruncmd:\n- reboot\n")},
+			want:  "no-runcmd",
+		},
+		"Include": {
+			parts: []cloudinit.PartReader{part("#include\nhttps://untrusted.example.com/user-data\n")},
+			want:  "trusted-includes",
+		},
+		"ArchiveCloudConfig": {
+			parts: []cloudinit.PartReader{part("#cloud-config-archive\n- type: text/cloud-config\n  content: |\n    runcmd:\n    - reboot\n")},
+			want:  "no-runcmd",
+		},
+		"ArchiveDetectedCloudConfig": {
+			parts: []cloudinit.PartReader{part("#cloud-config-archive\n- content: |\n    #cloud-config\n    runcmd:\n    - reboot\n")},
+			want:  "no-runcmd",
+		},
+		"ArchiveInclude": {
+			parts: []cloudinit.PartReader{part("#cloud-config-archive\n- type: text/x-include-url\n  content: https://untrusted.example.com/user-data\n")},
+			want:  "trusted-includes",
+		},
+		"NestedArchive": {
+			parts: []cloudinit.PartReader{part("#cloud-config-archive\n- content: |\n    #cloud-config-archive\n    - content: |\n        #include\n        https://untrusted.example.com/user-data\n")},
+			want:  "trusted-includes",
+		},
+		"PatchKey": {
+			parts: []cloudinit.PartReader{part(`#cloud-config-jsonp
+[{"op": "add", "path": "/runcmd", "value": ["reboot"]}]`)},
+			want: "no-runcmd",
+		},
+		"PatchIntoKey": {
+			parts: []cloudinit.PartReader{part(`#cloud-config-jsonp
+[{"op": "add", "path": "/runcmd/-", "value": "reboot"}]`)},
+			want: "no-runcmd",
+		},
+		"PatchRemove": {
+			parts: []cloudinit.PartReader{part(`#cloud-config-jsonp
+[{"op": "remove", "path": "/runcmd"}]`)},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := ""
+			err := Check(rules, tc.parts)
+			if v, ok := IsViolation(err); ok {
+				got = v.Rule
+			} else if err != nil {
+				t.Fatalf("Check(...): %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Check(...): violated rule: -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestVariables(t *testing.T) {
+	cases := map[string]struct {
+		part        part
+		cloudConfig map[string]interface{}
+		includes    []interface{}
+	}{
+		"ArchiveMergesEntries": {
+			part: "#cloud-config-archive\n" +
+				"- content: |\n    #cloud-config\n    packages: [curl]\n    users:\n      admin: {}\n" +
+				"- content: |\n    #cloud-config\n    packages: [git]\n    users:\n      ops: {}\n",
+			cloudConfig: map[string]interface{}{
+				"packages": []interface{}{"curl", "git"},
+				"users":    map[string]interface{}{"admin": map[string]interface{}{}, "ops": map[string]interface{}{}},
+			},
+			includes: []interface{}{},
+		},
+		"PatchValues": {
+			part: `#cloud-config-jsonp
+[{"op": "replace", "path": "/hostname", "value": "node-1"},
+ {"op": "add", "path": "/ntp/servers/-", "value": "ntp.example.com"},
+ {"op": "add", "path": "/swap/size", "value": 1024}]`,
+			cloudConfig: map[string]interface{}{
+				"hostname": "node-1",
+				"ntp":      []interface{}{"ntp.example.com"},
+				"swap":     []interface{}{int64(1024)},
+			},
+			includes: []interface{}{},
+		},
+		"ArchiveIncludes": {
+			part: "#cloud-config-archive\n" +
+				"- type: text/x-include-url\n  content: https://a.example.com/one\n" +
+				"- type: text/x-include-once-url\n  content: https://b.example.com/two\n",
+			cloudConfig: map[string]interface{}{},
+			includes:    []interface{}{"https://a.example.com/one", "https://b.example.com/two"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			vars, err := Variables([]cloudinit.PartReader{tc.part})
+			if err != nil {
+				t.Fatalf("Variables(...): %v", err)
+			}
+			got := vars["parts"].([]interface{})[0].(map[string]interface{})
+			if diff := cmp.Diff(tc.cloudConfig, got["cloudConfig"]); diff != "" {
+				t.Errorf("Variables(...): cloudConfig: -want, +got:\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.includes, got["includes"]); diff != "" {
+				t.Errorf("Variables(...): includes: -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestVariablesInvalid(t *testing.T) {
+	cases := map[string]part{
+		"CloudConfig": "#cloud-config\n- not a mapping\n",
+		"Archive":     "#cloud-config-archive\nnot: a list\n",
+		"Patch":       "#cloud-config-jsonp\n{}",
+	}
+	for name, p := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Variables([]cloudinit.PartReader{p}); err == nil {
+				t.Errorf("Variables(...): want error, got nil")
+			}
+		})
+	}
+}
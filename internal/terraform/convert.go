@@ -0,0 +1,380 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package terraform converts the cloudinit_config data sources of Terraform
+// configurations into Configs, which render the same documents.
+package terraform
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/crossplane-contrib/provider-cloudinit/apis/config/v1alpha1"
+)
+
+// Defaults of the Terraform cloudinit_config data source, which differ from
+// those of a Config.
+const (
+	defaultGzip         = true
+	defaultBase64Encode = true
+	defaultBoundary     = "MIMEBOUNDARY"
+
+	outputKey = "cloud-init"
+)
+
+// dataSources are the types of the data sources that are converted. The
+// template provider's data source predates the cloudinit provider.
+var dataSources = map[string]bool{
+	"cloudinit_config":          true,
+	"template_cloudinit_config": true,
+}
+
+// A Converter converts Terraform configuration files
+type Converter struct {
+	parser *hclparse.Parser
+
+	// Namespace is the namespace the converted Configs write their output to
+	Namespace string
+}
+
+// NewConverter returns a Converter writing the output of Configs to the
+// supplied namespace
+func NewConverter(namespace string) *Converter {
+	return &Converter{parser: hclparse.NewParser(), Namespace: namespace}
+}
+
+// Files returns the files parsed by the Converter, for writing diagnostics
+func (c *Converter) Files() map[string]*hcl.File {
+	return c.parser.Files()
+}
+
+// ConvertFile converts the cloudinit_config data sources of a Terraform
+// configuration file. Constructs that cannot be translated, such as
+// references to variables, are reported as warnings and skipped.
+func (c *Converter) ConvertFile(filename string) ([]*v1alpha1.Config, hcl.Diagnostics) {
+	f, diags := c.parser.ParseHCLFile(filename)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	body, ok := f.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil, diags
+	}
+
+	ctx := evalContext(filepath.Dir(filename))
+	cfgs := make([]*v1alpha1.Config, 0)
+	for _, b := range body.Blocks {
+		if b.Type != "data" || len(b.Labels) != 2 || !dataSources[b.Labels[0]] {
+			continue
+		}
+		cfg, d := c.convertDataSource(ctx, b)
+		diags = append(diags, d...)
+		cfgs = append(cfgs, cfg)
+	}
+	return cfgs, diags
+}
+
+func (c *Converter) convertDataSource(ctx *hcl.EvalContext, b *hclsyntax.Block) (*v1alpha1.Config, hcl.Diagnostics) {
+	name := strings.ReplaceAll(strings.ToLower(b.Labels[1]), "_", "-")
+	p := v1alpha1.ConfigParameters{
+		Gzip:         boolPtr(defaultGzip),
+		Base64Encode: boolPtr(defaultBase64Encode),
+		Boundary:     defaultBoundary,
+	}
+
+	var diags hcl.Diagnostics
+	for _, a := range sortedAttributes(b.Body) {
+		switch a.Name {
+		case "gzip", "base64_encode":
+			v, d := evalAs(ctx, a.Expr, cty.Bool)
+			diags = append(diags, d...)
+			if v.IsNull() {
+				continue
+			}
+			if a.Name == "gzip" {
+				p.Gzip = boolPtr(v.True())
+			} else {
+				p.Base64Encode = boolPtr(v.True())
+			}
+		case "boundary":
+			v, d := evalAs(ctx, a.Expr, cty.String)
+			diags = append(diags, d...)
+			if !v.IsNull() {
+				p.Boundary = v.AsString()
+			}
+		case "depends_on", "provider":
+			// meaningless outside of Terraform
+		default:
+			diags = append(diags, untranslatable(fmt.Sprintf("Cannot translate argument %q", a.Name),
+				"The argument has no equivalent in a Config and is ignored.", a.NameRange))
+		}
+	}
+	for _, pb := range b.Body.Blocks {
+		switch pb.Type {
+		case "part":
+			ps, d := convertPart(ctx, pb)
+			diags = append(diags, d...)
+			if ps != nil {
+				p.Parts = append(p.Parts, *ps)
+			}
+		case "lifecycle":
+			// meaningless outside of Terraform
+		default:
+			diags = append(diags, untranslatable(fmt.Sprintf("Cannot translate %s block", pb.Type),
+				"The block has no equivalent in a Config and is ignored.", pb.TypeRange))
+		}
+	}
+
+	return &v1alpha1.Config{
+		TypeMeta:   metav1.TypeMeta{APIVersion: v1alpha1.SchemeGroupVersion.String(), Kind: v1alpha1.ConfigKind},
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: v1alpha1.ConfigSpec{
+			ForProvider: p,
+			WriteCloudInitToRef: &v1alpha1.OutputSelector{DataKeySelector: v1alpha1.DataKeySelector{
+				NamespacedName: v1alpha1.NamespacedName{Name: name, Namespace: c.Namespace},
+				Key:            outputKey,
+			}},
+		},
+	}, diags
+}
+
+// convertPart converts a part block. Parts with arguments that cannot be
+// translated are skipped.
+func convertPart(ctx *hcl.EvalContext, b *hclsyntax.Block) (*v1alpha1.PartSpec, hcl.Diagnostics) {
+	ps := &v1alpha1.PartSpec{}
+	fields := map[string]*string{
+		"content":      &ps.Content,
+		"content_type": &ps.ContentType,
+		"filename":     &ps.Filename,
+		"merge_type":   &ps.MergeType,
+	}
+
+	var diags hcl.Diagnostics
+	skip := false
+	for _, a := range sortedAttributes(b.Body) {
+		f, ok := fields[a.Name]
+		if !ok {
+			diags = append(diags, untranslatable(fmt.Sprintf("Cannot translate argument %q", a.Name),
+				"The argument has no equivalent in a Config and is ignored.", a.NameRange))
+			continue
+		}
+		v, d := evalAs(ctx, a.Expr, cty.String)
+		diags = append(diags, d...)
+		if len(d) > 0 {
+			skip = true
+			continue
+		}
+		if !v.IsNull() {
+			*f = v.AsString()
+		}
+	}
+	if skip {
+		diags = append(diags, untranslatable("Part skipped",
+			"The part could not be translated, and is missing from the Config.", b.TypeRange))
+		return nil, diags
+	}
+	return ps, diags
+}
+
+// evalAs evaluates expr as a value of type t. Expressions that cannot be
+// evaluated outside of Terraform are reported as warnings, and evaluate to
+// null.
+func evalAs(ctx *hcl.EvalContext, expr hcl.Expression, t cty.Type) (cty.Value, hcl.Diagnostics) {
+	var diags hcl.Diagnostics
+	for _, tr := range expr.Variables() {
+		if tr.RootName() == "path" {
+			continue
+		}
+		diags = append(diags, untranslatable("Cannot translate reference",
+			fmt.Sprintf("%s is only known to Terraform. Only literal values, file() and templatefile() with literal variables are translated.", traversalString(tr)),
+			tr.SourceRange()))
+	}
+	if len(diags) > 0 {
+		return cty.NullVal(t), diags
+	}
+
+	v, d := expr.Value(ctx)
+	if d.HasErrors() {
+		return cty.NullVal(t), warnings(d)
+	}
+	v, err := convert.Convert(v, t)
+	if err != nil || !v.IsWhollyKnown() {
+		r := expr.Range()
+		return cty.NullVal(t), hcl.Diagnostics{untranslatable("Cannot translate expression",
+			fmt.Sprintf("The expression must evaluate to a %s.", t.FriendlyName()), r)}
+	}
+	return v, nil
+}
+
+// evalContext returns the variables and functions available to expressions
+// of files in dir. Paths are relative to dir.
+func evalContext(dir string) *hcl.EvalContext {
+	ctx := &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"path": cty.ObjectVal(map[string]cty.Value{
+				"module": cty.StringVal("."),
+				"root":   cty.StringVal("."),
+				"cwd":    cty.StringVal("."),
+			}),
+		},
+		Functions: map[string]function.Function{
+			"chomp":      stdlib.ChompFunc,
+			"concat":     stdlib.ConcatFunc,
+			"format":     stdlib.FormatFunc,
+			"formatlist": stdlib.FormatListFunc,
+			"indent":     stdlib.IndentFunc,
+			"join":       stdlib.JoinFunc,
+			"jsondecode": stdlib.JSONDecodeFunc,
+			"jsonencode": stdlib.JSONEncodeFunc,
+			"length":     stdlib.LengthFunc,
+			"lookup":     stdlib.LookupFunc,
+			"lower":      stdlib.LowerFunc,
+			"merge":      stdlib.MergeFunc,
+			"replace":    stdlib.ReplaceFunc,
+			"split":      stdlib.SplitFunc,
+			"title":      stdlib.TitleFunc,
+			"trim":       stdlib.TrimFunc,
+			"trimprefix": stdlib.TrimPrefixFunc,
+			"trimspace":  stdlib.TrimSpaceFunc,
+			"trimsuffix": stdlib.TrimSuffixFunc,
+			"upper":      stdlib.UpperFunc,
+			"file":       fileFunc(dir),
+		},
+	}
+	ctx.Functions["templatefile"] = templateFileFunc(dir, ctx.Functions)
+	return ctx
+}
+
+// fileFunc returns the file() function, reading files relative to dir
+func fileFunc(dir string) function.Function {
+	return function.New(&function.Spec{
+		Params: []function.Parameter{{Name: "path", Type: cty.String}},
+		Type:   function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+			b, err := ioutil.ReadFile(resolve(dir, args[0].AsString())) // nolint:gosec
+			if err != nil {
+				return cty.NilVal, err
+			}
+			return cty.StringVal(string(b)), nil
+		},
+	})
+}
+
+// templateFileFunc returns the templatefile() function, rendering templates
+// read relative to dir with the supplied functions
+func templateFileFunc(dir string, funcs map[string]function.Function) function.Function {
+	return function.New(&function.Spec{
+		Params: []function.Parameter{
+			{Name: "path", Type: cty.String},
+			{Name: "vars", Type: cty.DynamicPseudoType},
+		},
+		Type: function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, _ cty.Type) (cty.Value, error) {
+			path := resolve(dir, args[0].AsString())
+			src, err := ioutil.ReadFile(path) // nolint:gosec
+			if err != nil {
+				return cty.NilVal, err
+			}
+			expr, diags := hclsyntax.ParseTemplate(src, path, hcl.Pos{Line: 1, Column: 1})
+			if diags.HasErrors() {
+				return cty.NilVal, diags
+			}
+			vars := map[string]cty.Value{}
+			if v := args[1]; !v.IsNull() {
+				if !v.Type().IsObjectType() && !v.Type().IsMapType() {
+					return cty.NilVal, function.NewArgErrorf(1, "vars must be an object")
+				}
+				vars = v.AsValueMap()
+			}
+			// templates may not call templatefile, as in Terraform
+			tf := map[string]function.Function{}
+			for n, f := range funcs {
+				if n != "templatefile" {
+					tf[n] = f
+				}
+			}
+			v, diags := expr.Value(&hcl.EvalContext{Variables: vars, Functions: tf})
+			if diags.HasErrors() {
+				return cty.NilVal, diags
+			}
+			return convert.Convert(v, cty.String)
+		},
+	})
+}
+
+func resolve(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+func untranslatable(summary, detail string, r hcl.Range) *hcl.Diagnostic {
+	return &hcl.Diagnostic{Severity: hcl.DiagWarning, Summary: summary, Detail: detail, Subject: r.Ptr()}
+}
+
+// warnings returns diags as warnings
+func warnings(diags hcl.Diagnostics) hcl.Diagnostics {
+	out := make(hcl.Diagnostics, len(diags))
+	for i, d := range diags {
+		w := *d
+		w.Severity = hcl.DiagWarning
+		out[i] = &w
+	}
+	return out
+}
+
+func traversalString(tr hcl.Traversal) string {
+	b := &strings.Builder{}
+	for _, s := range tr {
+		switch t := s.(type) {
+		case hcl.TraverseRoot:
+			b.WriteString(t.Name)
+		case hcl.TraverseAttr:
+			b.WriteString("." + t.Name)
+		case hcl.TraverseIndex:
+			b.WriteString("[...]")
+		}
+	}
+	return b.String()
+}
+
+func sortedAttributes(b *hclsyntax.Body) []*hclsyntax.Attribute {
+	attrs := make([]*hclsyntax.Attribute, 0, len(b.Attributes))
+	for _, a := range b.Attributes {
+		attrs = append(attrs, a)
+	}
+	sort.Slice(attrs, func(i, j int) bool {
+		return attrs[i].SrcRange.Start.Byte < attrs[j].SrcRange.Start.Byte
+	})
+	return attrs
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
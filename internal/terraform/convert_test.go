@@ -0,0 +1,108 @@
+/*
+Copyright 2020 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package terraform
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/hashicorp/hcl/v2"
+	"sigs.k8s.io/yaml"
+)
+
+var update = flag.Bool("update", false, "update the golden files of testdata")
+
+// TestConvertFile compares the Configs and diagnostics converted from each
+// file of testdata with the golden files next to it. Run the test with
+// -update to rewrite the golden files after an intended change.
+func TestConvertFile(t *testing.T) {
+	cases := map[string]struct {
+		file    string
+		wantErr bool
+	}{
+		"Literal": {
+			file: "literal.tf",
+		},
+		"Files": {
+			file: "files.tf",
+		},
+		"Untranslatable": {
+			file: "untranslatable.tf",
+		},
+		"Invalid": {
+			file:    "invalid.tf",
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join("testdata", tc.file)
+			cfgs, diags := NewConverter("default").ConvertFile(path)
+			if diags.HasErrors() != tc.wantErr {
+				t.Fatalf("ConvertFile(...): want error %t, got %v", tc.wantErr, diags)
+			}
+
+			got, err := yaml.Marshal(cfgs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			golden(t, strings.TrimSuffix(path, ".tf")+".yaml", string(got))
+			golden(t, strings.TrimSuffix(path, ".tf")+".diags", diagnostics(diags))
+		})
+	}
+}
+
+// golden compares got with the content of the golden file at path
+func golden(t *testing.T, path, got string) {
+	t.Helper()
+	if *update {
+		if err := ioutil.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(path) // nolint:gosec
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(string(want), got); diff != "" {
+		t.Errorf("%s: -want, +got:\n%s", path, diff)
+	}
+}
+
+// diagnostics formats diags one per line, with the range they apply to
+func diagnostics(diags hcl.Diagnostics) string {
+	b := &strings.Builder{}
+	for _, d := range diags {
+		sev := "warning"
+		if d.Severity == hcl.DiagError {
+			sev = "error"
+		}
+		r := ""
+		if d.Subject != nil {
+			r = d.Subject.String()
+		}
+		fmt.Fprintf(b, "%s: %s: %s: %s\n", r, sev, d.Summary, d.Detail)
+	}
+	return b.String()
+}
@@ -0,0 +1,15 @@
+data "cloudinit_config" "bootstrap" {
+  part {
+    filename     = "setup.sh"
+    content_type = "text/x-shellscript"
+    content      = file("${path.module}/templates/setup.sh")
+  }
+
+  part {
+    content_type = "text/cloud-config"
+    content = templatefile("templates/users.tpl", {
+      users = ["alice", "bob"]
+      shell = "/bin/bash"
+    })
+  }
+}
@@ -0,0 +1,5 @@
+data "cloudinit_config" "invalid" {
+  part {
+    content = "unterminated
+  }
+}
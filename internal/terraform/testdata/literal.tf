@@ -0,0 +1,39 @@
+resource "aws_instance" "web" {
+  user_data = data.cloudinit_config.web.rendered
+}
+
+data "cloudinit_config" "web_server" {
+  gzip          = false
+  base64_encode = false
+  boundary      = "WEBBOUNDARY"
+
+  part {
+    content_type = "text/cloud-config"
+    filename     = "cloud-config.yaml"
+    merge_type   = "list(append)+dict(recurse_array)"
+    content      = <<-EOT
+      #cloud-config
+      packages:
+      - nginx
+    EOT
+  }
+
+  part {
+    content_type = "text/x-shellscript"
+    content      = "#!/bin/sh\necho ${upper("hello")}\n"
+  }
+
+  depends_on = [aws_instance.web]
+
+  lifecycle {
+    postcondition {
+      condition = true
+    }
+  }
+}
+
+data "template_cloudinit_config" "legacy" {
+  part {
+    content = "#cloud-config\nhostname: legacy\n"
+  }
+}
@@ -0,0 +1,6 @@
+#cloud-config
+users:
+%{ for u in users ~}
+- name: ${u}
+  shell: ${shell}
+%{ endfor ~}
@@ -0,0 +1,31 @@
+variable "gzip" {
+  type = bool
+}
+
+data "cloudinit_config" "partial" {
+  gzip          = var.gzip
+  base64_encode = true
+  count         = 2
+
+  part {
+    content_type = "text/cloud-config"
+    content      = "#cloud-config\nhostname: ${var.hostname}\n"
+  }
+
+  part {
+    content_type = "text/cloud-config"
+    content      = "#cloud-config\nhostname: partial\n"
+    priority     = 1
+  }
+
+  part {
+    content = ["not", "a", "string"]
+  }
+
+  dynamic "part" {
+    for_each = var.parts
+    content {
+      content = part.value
+    }
+  }
+}